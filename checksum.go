@@ -0,0 +1,148 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package uplink
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"storj.io/common/memory"
+	"storj.io/uplink/private/metaclient"
+)
+
+// ErrChecksumMismatch is returned when the downloaded data doesn't match the
+// checksums, which were stored with the object.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// keys of the plaintext checksums in the object metadata.
+const (
+	sha256Key = metaclient.SystemMetadataPrefix + "sha256"
+	crc32cKey = metaclient.SystemMetadataPrefix + "crc32c"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// checksums calculates the checksums of the plaintext.
+type checksums struct {
+	sha256 hash.Hash
+	crc32c hash.Hash32
+
+	// done is closed, when the checksums of the source are complete, and
+	// err is the error of reading it.
+	done chan struct{}
+	err  error
+}
+
+func newChecksums() *checksums {
+	return &checksums{
+		sha256: sha256.New(),
+		crc32c: crc32.New(crc32cTable),
+	}
+}
+
+// Write adds p to the checksums.
+func (sums *checksums) Write(p []byte) (n int, err error) {
+	_, _ = sums.sha256.Write(p)
+	_, _ = sums.crc32c.Write(p)
+	return len(p), nil
+}
+
+// sumSource adds the data of the source to the checksums in the background.
+// The source is read independently of the upload of its segments.
+func (sums *checksums) sumSource(ctx context.Context, source *io.SectionReader) {
+	sums.done = make(chan struct{})
+	go func() {
+		defer close(sums.done)
+
+		reader := io.NewSectionReader(source, 0, source.Size())
+		buffer := make([]byte, 32*memory.KiB)
+		for ctx.Err() == nil {
+			n, err := reader.Read(buffer)
+			_, _ = sums.Write(buffer[:n])
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				sums.err = err
+				return
+			}
+		}
+		sums.err = ctx.Err()
+	}()
+}
+
+// wait waits until the checksums of the source are complete.
+func (sums *checksums) wait() error {
+	if sums.done == nil {
+		return nil
+	}
+	<-sums.done
+	return sums.err
+}
+
+// addToMetadata stores the current checksums in the object metadata.
+func (sums *checksums) addToMetadata(metadata map[string]string) {
+	metadata[sha256Key] = hex.EncodeToString(sums.sha256.Sum(nil))
+	metadata[crc32cKey] = hex.EncodeToString(sums.crc32c.Sum(nil))
+}
+
+// checksumsFromMetadata returns the checksums stored in the object metadata.
+// The checksums are nil, when they are missing or invalid.
+func checksumsFromMetadata(metadata map[string]string) (sha256Sum, crc32cSum []byte) {
+	sha256Sum, err := hex.DecodeString(metadata[sha256Key])
+	if err != nil || len(sha256Sum) != sha256.Size {
+		sha256Sum = nil
+	}
+	crc32cSum, err = hex.DecodeString(metadata[crc32cKey])
+	if err != nil || len(crc32cSum) != crc32.Size {
+		crc32cSum = nil
+	}
+	return sha256Sum, crc32cSum
+}
+
+// checksumReader verifies the checksums of the data when it reaches EOF.
+type checksumReader struct {
+	reader    io.Reader
+	checksums *checksums
+	sha256    []byte
+	crc32c    []byte
+}
+
+// newChecksumReader returns a reader, which verifies the data read from reader
+// against the checksums stored in the object metadata. The reader is returned
+// as is, when the metadata doesn't contain any checksums.
+func newChecksumReader(reader io.Reader, metadata map[string]string) io.Reader {
+	sha256Sum, crc32cSum := checksumsFromMetadata(metadata)
+	if sha256Sum == nil && crc32cSum == nil {
+		return reader
+	}
+	return &checksumReader{
+		reader:    reader,
+		checksums: newChecksums(),
+		sha256:    sha256Sum,
+		crc32c:    crc32cSum,
+	}
+}
+
+func (reader *checksumReader) Read(p []byte) (n int, err error) {
+	n, err = reader.reader.Read(p)
+	_, _ = reader.checksums.Write(p[:n])
+
+	if errors.Is(err, io.EOF) {
+		if reader.sha256 != nil && !bytes.Equal(reader.sha256, reader.checksums.sha256.Sum(nil)) {
+			return n, errwrapf("%w: sha256", ErrChecksumMismatch)
+		}
+		if reader.crc32c != nil && !bytes.Equal(reader.crc32c, reader.checksums.crc32c.Sum(nil)) {
+			return n, errwrapf("%w: crc32c", ErrChecksumMismatch)
+		}
+	}
+
+	return n, err
+}
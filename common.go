@@ -15,6 +15,7 @@ import (
 	"storj.io/common/encryption"
 	"storj.io/common/errs2"
 	"storj.io/common/rpc/rpcstatus"
+	"storj.io/uplink/private/membudget"
 	"storj.io/uplink/private/metaclient"
 )
 
@@ -29,6 +30,10 @@ var ErrTooManyRequests = errors.New("too many requests")
 // ErrBandwidthLimitExceeded is returned when project will exceeded bandwidth limit.
 var ErrBandwidthLimitExceeded = errors.New("bandwidth limit exceeded")
 
+// ErrTransferMemoryExhausted is returned when a transfer can't start, because
+// Config.MaxTransferMemory is exhausted and Config.FailOnTransferMemoryExhausted is set.
+var ErrTransferMemoryExhausted = errors.New("transfer memory exhausted")
+
 // ErrPermissionDenied is returned when the request is denied due to invalid permissions.
 var ErrPermissionDenied = errors.New("permission denied")
 
@@ -36,12 +41,16 @@ func convertKnownErrors(err error, bucket, key string) error {
 	switch {
 	case errors.Is(err, io.EOF):
 		return err
+	case errors.Is(err, membudget.ErrExhausted):
+		return errwrapf("%w", ErrTransferMemoryExhausted)
 	case metaclient.ErrNoBucket.Has(err):
 		return errwrapf("%w (%q)", ErrBucketNameInvalid, bucket)
 	case metaclient.ErrNoPath.Has(err):
 		return errwrapf("%w (%q)", ErrObjectKeyInvalid, key)
 	case metaclient.ErrBucketNotFound.Has(err):
 		return errwrapf("%w (%q)", ErrBucketNotFound, bucket)
+	case metaclient.ErrPreconditionFailed.Has(err):
+		return errwrapf("%w (%q)", ErrPreconditionFailed, key)
 	case metaclient.ErrObjectNotFound.Has(err):
 		return errwrapf("%w (%q)", ErrObjectNotFound, key)
 	case encryption.ErrMissingEncryptionBase.Has(err):
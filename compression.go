@@ -0,0 +1,47 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package uplink
+
+import (
+	"strings"
+
+	"storj.io/uplink/private/compression"
+	"storj.io/uplink/private/metaclient"
+)
+
+// Compression is a codec, which compresses the object data before it's
+// encrypted and uploaded.
+type Compression string
+
+const (
+	// CompressionNone doesn't compress the data.
+	CompressionNone Compression = ""
+	// CompressionGzip compresses the data with gzip.
+	CompressionGzip Compression = "gzip"
+)
+
+func (codec Compression) valid() bool {
+	return codec == CompressionNone || compression.Codec(codec).Valid()
+}
+
+// customMetadata returns the metadata without the keys reserved for system metadata.
+func customMetadata(metadata map[string]string) CustomMetadata {
+	system := 0
+	for k := range metadata {
+		if strings.HasPrefix(k, metaclient.SystemMetadataPrefix) {
+			system++
+		}
+	}
+	if system == 0 {
+		return metadata
+	}
+
+	custom := make(CustomMetadata, len(metadata)-system)
+	for k, v := range metadata {
+		if !strings.HasPrefix(k, metaclient.SystemMetadataPrefix) {
+			custom[k] = v
+		}
+	}
+	return custom
+}
@@ -12,6 +12,9 @@ import (
 	"storj.io/common/rpc/rpcpool"
 	"storj.io/common/socket"
 	"storj.io/common/useragent"
+	"storj.io/uplink/private/eestream"
+	"storj.io/uplink/private/ratelimit"
+	"storj.io/uplink/private/segmentcache"
 )
 
 // Config defines configuration for using uplink library.
@@ -29,10 +32,151 @@ type Config struct {
 	// a connection. If DialContext is nil, it'll try to use an implementation with background congestion control.
 	DialContext func(ctx context.Context, network, address string) (net.Conn, error)
 
+	// UploadRateLimit and DownloadRateLimit limit the bandwidth used by all
+	// uploads to and downloads from the storage nodes of a project opened with
+	// this config. They can be overridden for a single upload or download.
+	UploadRateLimit   RateLimit
+	DownloadRateLimit RateLimit
+
+	// BufferSpill configures the buffers of the erasure encoding and decoding
+	// of uploads and downloads to store their data in temporary files, once
+	// they exceed a memory threshold. This reduces the memory used by many
+	// parallel transfers.
+	BufferSpill BufferSpill
+
+	// MaxTransferMemory limits the memory reserved by all segment uploads and
+	// downloads of a project opened with this config. A segment upload
+	// reserves the maximum size of its encoded data and a segment download
+	// the size of its decoding buffers. When the memory is exhausted new
+	// segment transfers wait until it's released, or fail with
+	// ErrTransferMemoryExhausted when FailOnTransferMemoryExhausted is set.
+	// Zero means no limit.
+	MaxTransferMemory             int64
+	FailOnTransferMemoryExhausted bool
+
+	// DownloadLongTail configures how many pieces of a segment are downloaded
+	// from the storage nodes and how the slow ones are handled. By default the
+	// downloads of all available pieces are started at once. It can be
+	// overridden for a single download.
+	DownloadLongTail *DownloadLongTail
+
+	// UploadLongTail configures when the piece uploads of a segment are
+	// finished. By default the remaining piece uploads are canceled once the
+	// optimal threshold of the redundancy scheme has been reached. It can be
+	// overridden for a single upload.
+	UploadLongTail UploadLongTail
+
+	// DownloadRetries is the number of times a download is resumed, when it
+	// fails in the middle of reading, because too many storage nodes failed
+	// or the network connection was interrupted. Other errors aren't retried.
+	// The remaining range is requested again after a short backoff, skipping
+	// the storage nodes, which failed. Zero disables resuming.
+	DownloadRetries int
+
+	// Cache configures a cache of the downloaded segments on the local disk.
+	// Repeated downloads of the same objects read their segments from it,
+	// as long as the objects haven't been replaced.
+	Cache SegmentCache
+
 	pool      *rpcpool.Pool
 	connector rpc.Connector
 }
 
+// RateLimit limits the bandwidth of transfers.
+type RateLimit struct {
+	// BytesPerSecond is the average rate of the transfers. Zero means no limit.
+	BytesPerSecond int64
+	// Burst is the maximum number of bytes, which can be transferred at once.
+	// It defaults to BytesPerSecond.
+	Burst int64
+}
+
+func (limit RateLimit) limiter() *ratelimit.Limiter {
+	return ratelimit.NewLimiter(limit.BytesPerSecond, limit.Burst)
+}
+
+// DownloadLongTail configures the piece downloads of segments to reduce the
+// latency caused by slow storage nodes.
+type DownloadLongTail struct {
+	// ExtraPieces is the number of piece downloads started in addition to
+	// the pieces required to reconstruct a segment. The other pieces are
+	// spares, which are started when a piece download fails or is slower
+	// than HedgeAfter. Negative value starts all available pieces at once.
+	ExtraPieces int
+	// HedgeAfter is the latency deadline of a piece download. When reading
+	// from it takes longer, a spare piece download is started. Zero disables
+	// hedging.
+	HedgeAfter time.Duration
+	// CancelSlow cancels the piece downloads, which are behind, once enough
+	// erasure shares have arrived from the others.
+	CancelSlow bool
+}
+
+func (longTail *DownloadLongTail) config() *eestream.LongTail {
+	if longTail == nil {
+		return nil
+	}
+	return &eestream.LongTail{
+		ExtraPieces: longTail.ExtraPieces,
+		HedgeAfter:  longTail.HedgeAfter,
+		CancelSlow:  longTail.CancelSlow,
+	}
+}
+
+// UploadLongTail configures when the piece uploads of segments are finished,
+// which trades the durability of the segments against the upload latency.
+type UploadLongTail struct {
+	// Timeout cancels the remaining piece uploads of a segment, once it
+	// passes after more pieces than the repair threshold have been uploaded.
+	// The segment is committed then also with fewer pieces than the optimal
+	// threshold. Zero disables the timeout.
+	Timeout time.Duration
+	// WaitForAll waits for all piece uploads of a segment to finish, instead
+	// of canceling the remaining ones once the optimal threshold is reached.
+	WaitForAll bool
+}
+
+func (longTail UploadLongTail) config() eestream.UploadLongTail {
+	return eestream.UploadLongTail{
+		Timeout:    longTail.Timeout,
+		WaitForAll: longTail.WaitForAll,
+	}
+}
+
+// SegmentCache configures an on-disk cache of the downloaded segments. The
+// least recently used segments are removed when the cache exceeds its
+// maximum size.
+//
+// The segments are stored decrypted, so the directory must be protected
+// accordingly. A segment is downloaded as a whole and stored, when any part
+// of it is read and it isn't in the cache.
+type SegmentCache struct {
+	// Directory is where the segments are stored. The cache is disabled,
+	// when it's empty. The directory shouldn't be used by other processes
+	// at the same time. The projects of a process, which are opened with the
+	// same directory, share the cache and must use the same MaxSize.
+	Directory string
+	// MaxSize is the maximum total size of the stored segments in bytes.
+	MaxSize int64
+}
+
+func (cache SegmentCache) open() (*segmentcache.Cache, error) {
+	if cache.Directory == "" {
+		return nil, nil
+	}
+	return segmentcache.Open(cache.Directory, cache.MaxSize)
+}
+
+// BufferSpill configures the transfer buffers to spill to temporary files.
+type BufferSpill struct {
+	// Directory is where the temporary files are created. It defaults to
+	// the default directory for temporary files.
+	Directory string
+	// MemoryThreshold is the number of bytes each buffer keeps in memory.
+	// Zero disables spilling.
+	MemoryThreshold int64
+}
+
 // getDialer returns a new rpc.Dialer corresponding to the config.
 //
 // NB: this is used with linkname in internal/expose.
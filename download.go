@@ -5,10 +5,18 @@ package uplink
 
 import (
 	"context"
+	"io"
+	"io/ioutil"
+	"time"
 
 	"github.com/zeebo/errs"
 
+	"storj.io/uplink/private/compression"
+	"storj.io/uplink/private/corruption"
+	"storj.io/uplink/private/eestream"
 	"storj.io/uplink/private/metaclient"
+	"storj.io/uplink/private/progress"
+	"storj.io/uplink/private/ratelimit"
 	"storj.io/uplink/private/storage/streams"
 	"storj.io/uplink/private/stream"
 )
@@ -20,6 +28,61 @@ type DownloadOptions struct {
 	Offset int64
 	// When Length is negative it will read until the end of the blob.
 	Length int64
+
+	// SkipDecompression returns the stored data of a compressed object as is.
+	// By default compressed objects are decompressed and Offset and Length
+	// refer to the uncompressed data, so a ranged download of a compressed
+	// object requests the whole stored object again after the range.
+	SkipDecompression bool
+
+	// Progress, when not nil, is called with the progress of the download
+	// every ProgressInterval, which defaults to one second, and once more when
+	// the download is closed. It's called from a separate goroutine.
+	Progress         func(Progress)
+	ProgressInterval time.Duration
+
+	// RateLimit, when not nil, overrides Config.DownloadRateLimit for this
+	// download. The download isn't counted towards the limit of the project.
+	RateLimit *RateLimit
+
+	// ReadAhead is the number of segments following the one being read,
+	// which are requested from the satellite and start downloading from the
+	// storage nodes in the background. It avoids stalls at the segment
+	// boundaries of sequential reads, but each segment read in advance uses
+	// its own connections and buffers. The segments are read in advance only
+	// while Config.MaxTransferMemory has memory left for them. Zero disables
+	// reading ahead.
+	ReadAhead int
+
+	// LongTail, when not nil, overrides Config.DownloadLongTail for this
+	// download.
+	LongTail *DownloadLongTail
+
+	// VerifyAllShares decodes every stripe with one more erasure share than
+	// required, so that corrupted data returned by storage nodes is detected
+	// and corrected. The pieces, whose data was corrected, are reported by
+	// Download.CorruptedPieces. It makes the download slower.
+	VerifyAllShares bool
+
+	// Version, when not zero, is the expected latest version of the object.
+	// It doesn't select an older version: the latest version is always
+	// downloaded, and the download fails with ErrObjectNotFound, when it's
+	// not the expected one. It ensures that the data of different uploads
+	// isn't mixed up, when the object is replaced.
+	Version uint32
+}
+
+// CorruptedPiece identifies a piece of a segment, whose storage node
+// returned corrupted data.
+type CorruptedPiece struct {
+	// SegmentPart and SegmentIndex are the position of the segment in the
+	// object.
+	SegmentPart  uint32
+	SegmentIndex uint32
+	// PieceNum is the number of the piece in the segment.
+	PieceNum int
+	// NodeID is the ID of the storage node, which stores the piece.
+	NodeID string
 }
 
 // DownloadObject starts a download from the specific key.
@@ -32,6 +95,9 @@ func (project *Project) DownloadObject(ctx context.Context, bucket, key string,
 	if key == "" {
 		return nil, errwrapf("%w (%q)", ErrObjectKeyInvalid, key)
 	}
+	if options != nil && options.ReadAhead < 0 {
+		return nil, packageError.New("read ahead must not be negative, got %v", options.ReadAhead)
+	}
 
 	var opts metaclient.DownloadOptions
 	switch {
@@ -61,6 +127,28 @@ func (project *Project) DownloadObject(ctx context.Context, bucket, key string,
 		}
 	}
 
+	var tracker *progress.Tracker
+	var reporter *progress.Reporter
+	var report *corruption.Report
+	if options != nil {
+		if options.RateLimit != nil {
+			ctx = ratelimit.WithLimiter(ctx, options.RateLimit.limiter())
+		}
+		if options.LongTail != nil {
+			ctx = eestream.WithLongTail(ctx, *options.LongTail.config())
+		}
+		if options.VerifyAllShares {
+			report = corruption.NewReport()
+			ctx = corruption.WithReport(ctx, report)
+		}
+		ctx, tracker, reporter = startProgress(ctx, options.Progress, options.ProgressInterval)
+		defer func() {
+			if err != nil {
+				reporter.Stop()
+			}
+		}()
+	}
+
 	// N.B. we always call dbCleanup which closes the db because
 	// closing it earlier has the benefit of returning a connection to
 	// the pool, so we try to do that as early as possible.
@@ -74,9 +162,48 @@ func (project *Project) DownloadObject(ctx context.Context, bucket, key string,
 	// TODO: handle DownloadObject & downloadInfo.ListSegments.More in the same location.
 	//       currently this code is rather disjoint.
 
+	var info compression.Info
+	var compressed bool
+	decompress := options == nil || !options.SkipDecompression
+	ranged := opts.Range.Mode != metaclient.StreamRangeAll
+
 	objectDownload, err := db.DownloadObject(ctx, bucket, key, opts)
-	if err != nil {
-		return nil, convertKnownErrors(err, bucket, key)
+	switch {
+	case err != nil:
+		// the range of a compressed object refers to the uncompressed data,
+		// so it can be outside of the stored data.
+		if !decompress || !ranged || metaclient.ErrObjectNotFound.Has(err) {
+			return nil, convertKnownErrors(err, bucket, key)
+		}
+		info, compressed = compressionInfo(ctx, db, bucket, key, options)
+		if !compressed {
+			return nil, convertKnownErrors(err, bucket, key)
+		}
+	default:
+		if err := checkVersion(key, objectDownload.Object, options); err != nil {
+			return nil, err
+		}
+		if decompress {
+			info, compressed, err = compression.InfoFromMetadata(objectDownload.Object.Metadata)
+			if err != nil {
+				return nil, packageError.Wrap(err)
+			}
+		}
+	}
+
+	if compressed {
+		// the segments of the whole stored object can be reused for reading
+		// the index and the data.
+		var allSegments *metaclient.DownloadInfo
+		if !ranged {
+			allSegments = &objectDownload
+		}
+		download, err = project.downloadCompressed(ctx, db, bucket, key, allSegments, info, options)
+		if err != nil {
+			return nil, err
+		}
+		download.tracker, download.reporter, download.report = tracker, reporter, report
+		return download, nil
 	}
 
 	// Return the connection to the pool as soon as we can.
@@ -90,11 +217,167 @@ func (project *Project) DownloadObject(ctx context.Context, bucket, key string,
 	}
 
 	streamRange := objectDownload.Range
-	return &Download{
+	streamDownload := stream.NewDownloadRange(ctx, objectDownload, streams, streamRange.Start, streamRange.Limit-streamRange.Start)
+	streamDownload.SetRetries(project.config.DownloadRetries)
+	if options != nil {
+		streamDownload.SetReadAhead(options.ReadAhead)
+	}
+	download = &Download{
 		streams:  streams,
-		download: stream.NewDownloadRange(ctx, objectDownload, streams, streamRange.Start, streamRange.Limit-streamRange.Start),
+		download: streamDownload,
 		bucket:   bucket,
 		object:   convertObject(&objectDownload.Object),
+		tracker:  tracker,
+		reporter: reporter,
+		report:   report,
+	}
+	download.reader = download.download
+
+	// the checksums can be verified only when the whole object is downloaded,
+	// but not when it's stored data of a compressed object.
+	_, compressed, _ = compression.InfoFromMetadata(objectDownload.Object.Metadata)
+	if !compressed && streamRange.Start == 0 && streamRange.Limit == objectDownload.Object.Size {
+		download.reader = newChecksumReader(download.reader, objectDownload.Object.Metadata)
+	}
+
+	return download, nil
+}
+
+// compressionInfo returns the compression info of the object, when it's
+// compressed. It's requested only after a ranged download failed, because the
+// range of the uncompressed data was outside of the stored data.
+func compressionInfo(ctx context.Context, db *metaclient.DB, bucket, key string, options *DownloadOptions) (info compression.Info, compressed bool) {
+	var version uint32
+	if options != nil {
+		version = options.Version
+	}
+	object, err := db.GetObjectVersion(ctx, bucket, key, version)
+	if err != nil {
+		return compression.Info{}, false
+	}
+	info, compressed, err = compression.InfoFromMetadata(object.Metadata)
+	return info, compressed && err == nil
+}
+
+// checkVersion returns ErrObjectNotFound, when the downloaded latest version
+// of the object isn't the version expected by the options.
+func checkVersion(key string, object metaclient.Object, options *DownloadOptions) error {
+	if options == nil || options.Version == 0 || object.Version == options.Version {
+		return nil
+	}
+	return errwrapf("%w (%q version %d)", ErrObjectNotFound, key, options.Version)
+}
+
+// downloadCompressed starts a download of the uncompressed range of a
+// compressed object. allSegments is the download info of the whole stored
+// object, when it's already known; otherwise it's requested.
+func (project *Project) downloadCompressed(ctx context.Context, db *metaclient.DB, bucket, key string, allSegments *metaclient.DownloadInfo, info compression.Info, options *DownloadOptions) (_ *Download, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if allSegments == nil {
+		objectDownload, err := db.DownloadObject(ctx, bucket, key, metaclient.DownloadOptions{
+			Range: metaclient.StreamRange{Mode: metaclient.StreamRangeAll},
+		})
+		if err != nil {
+			return nil, convertKnownErrors(err, bucket, key)
+		}
+		if err := checkVersion(key, objectDownload.Object, options); err != nil {
+			return nil, err
+		}
+		// the object could have been replaced since its info was requested.
+		var compressed bool
+		info, compressed, err = compression.InfoFromMetadata(objectDownload.Object.Metadata)
+		if err != nil {
+			return nil, packageError.Wrap(err)
+		}
+		if !compressed {
+			return nil, packageError.New("object %q was replaced during download", key)
+		}
+		allSegments = &objectDownload
+	}
+	objectDownload := *allSegments
+
+	offset, length := int64(0), info.Size
+	if options != nil {
+		switch {
+		case options.Offset < 0:
+			offset = info.Size + options.Offset
+			if offset < 0 {
+				offset = 0
+			}
+		case options.Offset > info.Size:
+			offset = info.Size
+		default:
+			offset = options.Offset
+		}
+		length = info.Size - offset
+		if options.Length >= 0 && options.Length < length {
+			length = options.Length
+		}
+	}
+
+	dataSize := objectDownload.Object.Size - info.IndexSize
+	if dataSize < 0 {
+		return nil, packageError.New("invalid compression index size %d", info.IndexSize)
+	}
+
+	streams, err := project.getStreamsStore(ctx)
+	if err != nil {
+		return nil, convertKnownErrors(err, bucket, key)
+	}
+	defer func() {
+		if err != nil {
+			err = errs.Combine(err, streams.Close())
+		}
+	}()
+
+	// downloadRange returns a download of the specified range of the stored data.
+	downloadRange := func(info metaclient.DownloadInfo, start, limit int64) *stream.Download {
+		download := stream.NewDownloadRange(ctx, info, streams, start, limit-start)
+		download.SetRetries(project.config.DownloadRetries)
+		if options != nil {
+			download.SetReadAhead(options.ReadAhead)
+		}
+		return download
+	}
+
+	start, limit, skip := int64(0), dataSize, int64(0)
+	if offset != 0 || length != info.Size {
+		indexDownload := downloadRange(stream.WithoutLimits(objectDownload), dataSize, objectDownload.Object.Size)
+		index, err := ioutil.ReadAll(indexDownload)
+		err = errs.Combine(err, indexDownload.Close())
+		if err != nil {
+			return nil, convertKnownErrors(err, bucket, key)
+		}
+
+		frames, err := compression.ParseIndex(index)
+		if err != nil {
+			return nil, packageError.Wrap(err)
+		}
+		start, limit, skip, err = compression.FrameRange(info, frames, offset, length)
+		if err != nil {
+			return nil, packageError.Wrap(err)
+		}
+	}
+
+	dataDownload := downloadRange(objectDownload, start, limit)
+
+	var reader io.Reader
+	reader, err = compression.NewReader(dataDownload, info.Codec, skip, length)
+	if err != nil {
+		return nil, packageError.Wrap(errs.Combine(err, dataDownload.Close()))
+	}
+	if offset == 0 && length == info.Size {
+		reader = newChecksumReader(reader, objectDownload.Object.Metadata)
+	}
+
+	return &Download{
+		streams:    streams,
+		download:   dataDownload,
+		reader:     reader,
+		compressed: true,
+		bucket:     bucket,
+		object:     convertObject(&objectDownload.Object),
 	}, nil
 }
 
@@ -104,6 +387,16 @@ type Download struct {
 	object   *Object
 	bucket   string
 	streams  *streams.Store
+
+	// reader reads the data of the download, decompressing and verifying
+	// it when necessary.
+	reader io.Reader
+	// compressed is set when reader decompresses the data.
+	compressed bool
+
+	tracker  *progress.Tracker
+	reporter *progress.Reporter
+	report   *corruption.Report
 }
 
 // Info returns the last information about the object.
@@ -114,15 +407,294 @@ func (download *Download) Info() *Object {
 // Read downloads up to len(p) bytes into p from the object's data stream.
 // It returns the number of bytes read (0 <= n <= len(p)) and any error encountered.
 func (download *Download) Read(p []byte) (n int, err error) {
-	n, err = download.download.Read(p)
+	n, err = download.reader.Read(p)
+	download.tracker.AddPlainBytes(int64(n))
 	return n, convertKnownErrors(err, download.bucket, download.object.Key)
 }
 
+// Seek sets the offset for the next Read. The offset is relative to the start
+// of the downloaded range, which is set with Offset and Length in
+// DownloadOptions, and it can't be moved outside of it. The checksums of the
+// object aren't verified after seeking.
+//
+// Seeking isn't supported for the decompressed data of compressed objects.
+func (download *Download) Seek(offset int64, whence int) (_ int64, err error) {
+	if download.compressed {
+		return 0, packageError.New("seeking is not supported for compressed objects")
+	}
+
+	offset, err = download.download.Seek(offset, whence)
+	if err != nil {
+		return 0, convertKnownErrors(err, download.bucket, download.object.Key)
+	}
+	download.reader = download.download
+	return offset, nil
+}
+
+// ReadAt reads len(p) bytes into p starting at offset relative to the start
+// of the downloaded range. It doesn't change the offset used by Read.
+// The segment information is reused, so ReadAt doesn't download the object
+// information again. Multiple ReadAt calls can run concurrently.
+//
+// ReadAt isn't supported for the decompressed data of compressed objects.
+func (download *Download) ReadAt(p []byte, offset int64) (n int, err error) {
+	if download.compressed {
+		return 0, packageError.New("reading at offset is not supported for compressed objects")
+	}
+
+	n, err = download.download.ReadAt(p, offset)
+	download.tracker.AddPlainBytes(int64(n))
+	return n, convertKnownErrors(err, download.bucket, download.object.Key)
+}
+
+// CorruptedPieces returns the pieces, which returned corrupted data so far.
+// They are reported only by downloads with VerifyAllShares. Segments read
+// from Config.Cache aren't verified.
+func (download *Download) CorruptedPieces() []CorruptedPiece {
+	pieces := download.report.Pieces()
+	if len(pieces) == 0 {
+		return nil
+	}
+
+	corrupted := make([]CorruptedPiece, 0, len(pieces))
+	for _, piece := range pieces {
+		corrupted = append(corrupted, CorruptedPiece{
+			SegmentPart:  uint32(piece.Segment.PartNumber),
+			SegmentIndex: uint32(piece.Segment.Index),
+			PieceNum:     piece.PieceNum,
+			NodeID:       piece.NodeID.String(),
+		})
+	}
+	return corrupted
+}
+
 // Close closes the reader of the download.
 func (download *Download) Close() error {
+	defer download.reporter.Stop()
+
 	err := errs.Combine(
 		download.download.Close(),
 		download.streams.Close(),
 	)
 	return convertKnownErrors(err, download.bucket, download.object.Key)
 }
+
+// Range is a range of the data of an object.
+type Range struct {
+	// When Offset is negative the range is the suffix of the object.
+	// Combining negative offset and positive length is not supported.
+	Offset int64
+	// When Length is negative the range ends at the end of the object.
+	Length int64
+}
+
+// DownloadRangesOptions contains additional options for downloading ranges.
+type DownloadRangesOptions struct {
+	// RateLimit, when not nil, overrides Config.DownloadRateLimit for this
+	// download. The download isn't counted towards the limit of the project.
+	RateLimit *RateLimit
+
+	// LongTail, when not nil, overrides Config.DownloadLongTail for this
+	// download.
+	LongTail *DownloadLongTail
+}
+
+// DownloadRanges starts a download of multiple ranges of the object. The
+// object information and its segments are requested from the satellite only
+// once for all ranges. The ranges, which fall into the same segment, are
+// read with a single request to the storage nodes, including the data
+// between them.
+//
+// The ranges are read one after another with Next and Read of the returned
+// download. Reading the ranges in the order of their offsets is the most
+// efficient. The checksums of the object aren't verified and the ranges of
+// compressed objects aren't supported.
+func (project *Project) DownloadRanges(ctx context.Context, bucket, key string, ranges []Range, options *DownloadRangesOptions) (_ *RangesDownload, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if bucket == "" {
+		return nil, errwrapf("%w (%q)", ErrBucketNameInvalid, bucket)
+	}
+	if key == "" {
+		return nil, errwrapf("%w (%q)", ErrObjectKeyInvalid, key)
+	}
+	if len(ranges) == 0 {
+		return nil, packageError.New("no ranges to download")
+	}
+
+	// request the smallest range of the stream, which contains all ranges.
+	start, limit, suffix, toEnd := int64(-1), int64(0), false, false
+	for _, rng := range ranges {
+		switch {
+		case rng.Offset < 0:
+			if rng.Length >= 0 {
+				return nil, packageError.New("suffix requires length to be negative, got %v", rng.Length)
+			}
+			suffix = true
+		case rng.Length < 0:
+			toEnd = true
+		case rng.Offset+rng.Length > limit:
+			limit = rng.Offset + rng.Length
+		}
+		if rng.Offset >= 0 && (start < 0 || rng.Offset < start) {
+			start = rng.Offset
+		}
+	}
+
+	var opts metaclient.DownloadOptions
+	switch {
+	case suffix:
+		opts.Range = metaclient.StreamRange{
+			Mode: metaclient.StreamRangeAll,
+		}
+	case toEnd:
+		opts.Range = metaclient.StreamRange{
+			Mode:  metaclient.StreamRangeStart,
+			Start: start,
+		}
+	default:
+		opts.Range = metaclient.StreamRange{
+			Mode:  metaclient.StreamRangeStartLimit,
+			Start: start,
+			Limit: limit,
+		}
+	}
+
+	if options != nil {
+		if options.RateLimit != nil {
+			ctx = ratelimit.WithLimiter(ctx, options.RateLimit.limiter())
+		}
+		if options.LongTail != nil {
+			ctx = eestream.WithLongTail(ctx, *options.LongTail.config())
+		}
+	}
+
+	db, err := project.dialMetainfoDB(ctx)
+	if err != nil {
+		return nil, convertKnownErrors(err, bucket, key)
+	}
+	defer func() { err = errs.Combine(err, db.Close()) }()
+
+	objectDownload, err := db.DownloadObject(ctx, bucket, key, opts)
+	if err != nil {
+		return nil, convertKnownErrors(err, bucket, key)
+	}
+
+	_, compressed, err := compression.InfoFromMetadata(objectDownload.Object.Metadata)
+	if err != nil {
+		return nil, packageError.Wrap(err)
+	}
+	if compressed {
+		return nil, packageError.New("ranges of compressed objects are not supported")
+	}
+
+	// Return the connection to the pool as soon as we can.
+	if err := db.Close(); err != nil {
+		return nil, convertKnownErrors(err, bucket, key)
+	}
+
+	streams, err := project.getStreamsStore(ctx)
+	if err != nil {
+		return nil, convertKnownErrors(err, bucket, key)
+	}
+
+	size := objectDownload.Object.Size
+	resolved := make([]Range, len(ranges))
+	streamRanges := make([]stream.Range, len(ranges))
+	for i, rng := range ranges {
+		offset, length := rng.Offset, rng.Length
+		if offset < 0 {
+			offset += size
+			if offset < 0 {
+				offset = 0
+			}
+		}
+		if offset > size {
+			offset = size
+		}
+		if length < 0 || offset+length > size {
+			length = size - offset
+		}
+		resolved[i] = Range{Offset: offset, Length: length}
+		streamRanges[i] = stream.Range{Offset: offset, Length: length}
+	}
+
+	return &RangesDownload{
+		ranges:   stream.NewRanges(ctx, objectDownload, streams, streamRanges),
+		streams:  streams,
+		bucket:   bucket,
+		object:   convertObject(&objectDownload.Object),
+		resolved: resolved,
+	}, nil
+}
+
+// RangesDownload is a download of multiple ranges of an object.
+type RangesDownload struct {
+	ranges  *stream.Ranges
+	streams *streams.Store
+	bucket  string
+	object  *Object
+
+	resolved []Range
+	next     int
+	reader   io.Reader
+	err      error
+}
+
+// Info returns the information about the object.
+func (download *RangesDownload) Info() *Object {
+	return download.object
+}
+
+// Next prepares the next range for reading with Read. The ranges are read in
+// the order they were passed to DownloadRanges. It returns false, when there
+// are no more ranges or an error happened.
+func (download *RangesDownload) Next() bool {
+	if download.err != nil || download.next >= len(download.resolved) {
+		download.reader = nil
+		return false
+	}
+
+	reader, err := download.ranges.Reader(download.next)
+	if err != nil {
+		download.err = convertKnownErrors(err, download.bucket, download.object.Key)
+		download.reader = nil
+		return false
+	}
+	download.reader = reader
+	download.next++
+	return true
+}
+
+// Range returns the current range with the offset and the length resolved
+// against the size of the object.
+func (download *RangesDownload) Range() Range {
+	if download.reader == nil {
+		return Range{}
+	}
+	return download.resolved[download.next-1]
+}
+
+// Read reads up to len(p) bytes of the current range into p. It returns
+// io.EOF at the end of the range.
+func (download *RangesDownload) Read(p []byte) (n int, err error) {
+	if download.reader == nil {
+		return 0, packageError.New("no current range, call Next first")
+	}
+	n, err = download.reader.Read(p)
+	return n, convertKnownErrors(err, download.bucket, download.object.Key)
+}
+
+// Err returns the error, if one happened while preparing a range.
+func (download *RangesDownload) Err() error {
+	return download.err
+}
+
+// Close closes the download.
+func (download *RangesDownload) Close() error {
+	err := errs.Combine(
+		download.ranges.Close(),
+		download.streams.Close(),
+	)
+	return convertKnownErrors(err, download.bucket, download.object.Key)
+}
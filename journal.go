@@ -0,0 +1,158 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package uplink
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/zeebo/errs"
+
+	"storj.io/common/storj"
+	"storj.io/uplink/private/storage/streams"
+)
+
+// UploadJournal stores the progress of a resumable upload.
+//
+// The journal is used by UploadObject when it's set in UploadOptions.
+// Project.ResumeUpload continues an upload recorded in a journal.
+type UploadJournal interface {
+	// Load returns the last saved state. It returns a zero state when
+	// nothing has been saved yet.
+	Load() (UploadJournalState, error)
+	// Save replaces the saved state.
+	Save(UploadJournalState) error
+}
+
+// UploadJournalState is the progress of a resumable upload.
+type UploadJournalState struct {
+	Bucket string
+	Key    string
+	// UploadID identifies the uncommitted object. It can be used with
+	// AbortUpload to discard the upload.
+	UploadID string
+	// Segments contains the plain sizes of the committed segments, in order.
+	Segments []int64
+	// Committed is true when the object has been committed.
+	Committed bool
+}
+
+// Offset returns the offset in the uploaded data from which a resumed
+// upload continues.
+func (state UploadJournalState) Offset() (offset int64) {
+	for _, size := range state.Segments {
+		offset += size
+	}
+	return offset
+}
+
+// FileUploadJournal is an UploadJournal that is stored in a local file.
+type FileUploadJournal struct {
+	path string
+}
+
+// NewFileUploadJournal returns a journal which is stored in the file at path.
+func NewFileUploadJournal(path string) *FileUploadJournal {
+	return &FileUploadJournal{path: path}
+}
+
+// Load returns the state stored in the file.
+func (journal *FileUploadJournal) Load() (_ UploadJournalState, err error) {
+	data, err := ioutil.ReadFile(journal.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return UploadJournalState{}, nil
+		}
+		return UploadJournalState{}, packageError.Wrap(err)
+	}
+
+	var state UploadJournalState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return UploadJournalState{}, packageError.Wrap(err)
+	}
+	return state, nil
+}
+
+// Save replaces the state stored in the file.
+func (journal *FileUploadJournal) Save(state UploadJournalState) (err error) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return packageError.Wrap(err)
+	}
+
+	// write to a temporary file first, so that a crash doesn't leave a partial journal.
+	tmp, err := ioutil.TempFile(filepath.Dir(journal.path), filepath.Base(journal.path)+".*.tmp")
+	if err != nil {
+		return packageError.Wrap(err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	err = errs.Combine(err, tmp.Close())
+	if err != nil {
+		return packageError.Wrap(err)
+	}
+
+	return packageError.Wrap(os.Rename(tmp.Name(), journal.path))
+}
+
+// streamsJournal records the progress reported by the streams store in an UploadJournal.
+type streamsJournal struct {
+	journal UploadJournal
+	state   UploadJournalState
+}
+
+func (journal *streamsJournal) BeginObject(streamID storj.StreamID) error {
+	journal.state.UploadID = base58.CheckEncode(streamID[:], 1)
+	journal.state.Segments = nil
+	return journal.save()
+}
+
+func (journal *streamsJournal) CommitSegment(index int64, plainSize int64) error {
+	if index != int64(len(journal.state.Segments)) {
+		return packageError.New("journal: unexpected segment %d, expected %d", index, len(journal.state.Segments))
+	}
+	journal.state.Segments = append(journal.state.Segments, plainSize)
+	return journal.save()
+}
+
+func (journal *streamsJournal) CommitObject() error {
+	journal.state.Committed = true
+	return journal.save()
+}
+
+func (journal *streamsJournal) save() error {
+	state := journal.state
+	state.Segments = append([]int64(nil), journal.state.Segments...)
+	return journal.journal.Save(state)
+}
+
+// resumeState converts a journal state to the resume state of the streams store.
+func (state UploadJournalState) resumeState() (*streams.ResumeState, error) {
+	decodedStreamID, version, err := base58.CheckDecode(state.UploadID)
+	if err != nil || version != 1 {
+		return nil, packageError.Wrap(ErrUploadIDInvalid)
+	}
+
+	streamID, err := storj.StreamIDFromBytes(decodedStreamID)
+	if err != nil {
+		return nil, packageError.Wrap(err)
+	}
+
+	return &streams.ResumeState{
+		StreamID:     streamID,
+		SegmentSizes: append([]int64(nil), state.Segments...),
+	}, nil
+}
@@ -0,0 +1,42 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package uplink_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/uplink"
+)
+
+func TestFileUploadJournal(t *testing.T) {
+	journal := uplink.NewFileUploadJournal(filepath.Join(t.TempDir(), "upload.journal"))
+
+	state, err := journal.Load()
+	require.NoError(t, err)
+	require.Equal(t, uplink.UploadJournalState{}, state)
+	require.Zero(t, state.Offset())
+
+	expected := uplink.UploadJournalState{
+		Bucket:   "bucket",
+		Key:      "key",
+		UploadID: "upload-id",
+		Segments: []int64{64, 64, 10},
+	}
+	require.NoError(t, journal.Save(expected))
+
+	state, err = journal.Load()
+	require.NoError(t, err)
+	require.Equal(t, expected, state)
+	require.EqualValues(t, 138, state.Offset())
+
+	expected.Committed = true
+	require.NoError(t, journal.Save(expected))
+
+	state, err = journal.Load()
+	require.NoError(t, err)
+	require.True(t, state.Committed)
+}
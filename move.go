@@ -17,7 +17,16 @@ import (
 
 // MoveObjectOptions options for MoveObject method.
 type MoveObjectOptions struct {
-	// may contain StreamID and Version in the future
+	// Version and StreamID, when set, are the expected version and stream ID
+	// of the object. The move fails with ErrPreconditionFailed, when the
+	// object doesn't match them.
+	//
+	// The object, whose move was begun, is checked before the move is
+	// finished. The begun move doesn't change anything, so it's only left
+	// unfinished, when the check fails. Once checked, the move fails, when
+	// the object is replaced before it's finished.
+	Version  uint32
+	StreamID []byte
 }
 
 // MoveObject moves object to a different bucket or/and key.
@@ -65,6 +74,16 @@ func (project *Project) MoveObject(ctx context.Context, oldbucket, oldkey, newbu
 		return convertKnownErrors(err, oldbucket, oldkey)
 	}
 
+	if options != nil {
+		// the move is finished only for the stream, which was begun, so it's
+		// the one checked. The begun move is left unfinished, when the check
+		// fails, and nothing is moved.
+		precondition := newPrecondition(options.Version, options.StreamID)
+		if err := precondition.CheckStreamID(response.StreamID); err != nil {
+			return convertKnownErrors(err, oldbucket, oldkey)
+		}
+	}
+
 	oldDerivedKey, err := deriveContentKey(project, oldbucket, oldkey)
 	if err != nil {
 		return packageError.Wrap(err)
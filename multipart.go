@@ -19,6 +19,7 @@ import (
 	"storj.io/common/pb"
 	"storj.io/common/storj"
 	"storj.io/uplink/private/metaclient"
+	"storj.io/uplink/private/progress"
 	"storj.io/uplink/private/storage/streams"
 	"storj.io/uplink/private/stream"
 )
@@ -241,7 +242,9 @@ func (project *Project) UploadPart(ctx context.Context, bucket, key, uploadID st
 		part: &Part{
 			PartNumber: partNumber,
 		},
+		tracker: progress.NewTracker(),
 	}
+	ctx = progress.WithTracker(ctx, upload.tracker)
 
 	streams, err := project.getStreamsStore(ctx)
 	if err != nil {
@@ -402,6 +405,9 @@ type PartUpload struct {
 	part    *Part
 	streams *streams.Store
 	etag    []byte
+
+	tracker  *progress.Tracker
+	reporter *progress.Reporter
 }
 
 // Write uploads len(p) bytes from p to the object's data stream.
@@ -409,9 +415,33 @@ type PartUpload struct {
 // and any error encountered that caused the write to stop early.
 func (upload *PartUpload) Write(p []byte) (int, error) {
 	n, err := upload.upload.Write(p)
+	upload.tracker.AddPlainBytes(int64(n))
 	return n, convertKnownErrors(err, upload.bucket, upload.key)
 }
 
+// SetProgress starts calling fn with the progress of the part upload every
+// interval, which defaults to one second, and once more when the part upload
+// is committed or aborted. fn is called from a separate goroutine.
+func (upload *PartUpload) SetProgress(fn func(Progress), interval time.Duration) error {
+	upload.mu.Lock()
+	defer upload.mu.Unlock()
+
+	if upload.aborted {
+		return errwrapf("%w: upload aborted", ErrUploadDone)
+	}
+	if upload.closed {
+		return errwrapf("%w: already committed", ErrUploadDone)
+	}
+	if upload.reporter != nil {
+		return packageError.New("progress already set")
+	}
+
+	if fn != nil {
+		upload.reporter = newProgressReporter(upload.tracker, fn, interval)
+	}
+	return nil
+}
+
 // SetETag sets ETag for a part.
 func (upload *PartUpload) SetETag(etag []byte) error {
 	upload.mu.Lock()
@@ -444,6 +474,7 @@ func (upload *PartUpload) Commit() error {
 	}
 
 	upload.closed = true
+	defer upload.reporter.Stop()
 
 	err := errs.Combine(
 		upload.upload.Close(),
@@ -468,6 +499,7 @@ func (upload *PartUpload) Abort() error {
 
 	upload.aborted = true
 	upload.cancel()
+	defer upload.reporter.Stop()
 
 	err := errs.Combine(
 		upload.upload.Abort(),
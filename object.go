@@ -13,6 +13,7 @@ import (
 
 	"github.com/zeebo/errs"
 
+	"storj.io/uplink/private/compression"
 	"storj.io/uplink/private/metaclient"
 )
 
@@ -22,12 +23,23 @@ var ErrObjectKeyInvalid = errors.New("object key invalid")
 // ErrObjectNotFound is returned when the object is not found.
 var ErrObjectNotFound = errors.New("object not found")
 
+// ErrPreconditionFailed is returned when the object doesn't match the
+// expected version or stream ID of an operation.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
 // Object contains information about an object.
 type Object struct {
 	Key string
 	// IsPrefix indicates whether the Key is a prefix for other objects.
 	IsPrefix bool
 
+	// Version is the version of the object and StreamID identifies its data.
+	// Each upload to the key creates a new version with a new stream ID.
+	// They aren't set for prefixes. StreamID is opaque and it isn't returned
+	// by ListObjects.
+	Version  uint32
+	StreamID []byte
+
 	System SystemMetadata
 	Custom CustomMetadata
 }
@@ -37,6 +49,20 @@ type SystemMetadata struct {
 	Created       time.Time
 	Expires       time.Time
 	ContentLength int64
+
+	// Compression is the codec, which was used to compress the object data.
+	// For compressed objects ContentLength is the size of the stored data,
+	// including the compression index, and UncompressedLength is the size of
+	// the original data. They are known only when the custom metadata of the
+	// object is available.
+	Compression        Compression
+	UncompressedLength int64
+
+	// SHA256 and CRC32C are the checksums of the uploaded data. They are
+	// nil when the object was uploaded without checksums or when the custom
+	// metadata of the object isn't available.
+	SHA256 []byte
+	CRC32C []byte
 }
 
 // CustomMetadata contains custom user metadata about the object.
@@ -96,26 +122,79 @@ func (project *Project) StatObject(ctx context.Context, bucket, key string) (inf
 	return convertObject(&obj), nil
 }
 
+// StatObjectVersion returns information about the specific version of an
+// object. It returns ErrObjectNotFound, when the version no longer exists.
+func (project *Project) StatObjectVersion(ctx context.Context, bucket, key string, version uint32) (info *Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if version == 0 {
+		return nil, packageError.New("version must be positive")
+	}
+
+	db, err := project.dialMetainfoDB(ctx)
+	if err != nil {
+		return nil, convertKnownErrors(err, bucket, key)
+	}
+	defer func() { err = errs.Combine(err, db.Close()) }()
+
+	obj, err := db.GetObjectVersion(ctx, bucket, key, version)
+	if err != nil {
+		return nil, convertKnownErrors(err, bucket, key)
+	}
+
+	return convertObject(&obj), nil
+}
+
 // DeleteObject deletes the object at the specific key.
 func (project *Project) DeleteObject(ctx context.Context, bucket, key string) (deleted *Object, err error) {
 	defer mon.Task()(&ctx)(&err)
 
+	return project.DeleteObjectWithOptions(ctx, bucket, key, nil)
+}
+
+// DeleteObjectOptions contains additional options for deleting an object.
+type DeleteObjectOptions struct {
+	// Version and StreamID, when set, are the expected version and stream ID
+	// of the object. The deletion fails with ErrPreconditionFailed, when the
+	// object doesn't match them.
+	//
+	// The satellite always deletes the latest version, so the object is
+	// checked right before it's deleted. When it's replaced in between, the
+	// other object is deleted and returned together with
+	// ErrPreconditionFailed.
+	Version  uint32
+	StreamID []byte
+}
+
+// DeleteObjectWithOptions deletes the object at the specific key.
+func (project *Project) DeleteObjectWithOptions(ctx context.Context, bucket, key string, options *DeleteObjectOptions) (deleted *Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
 	db, err := project.dialMetainfoDB(ctx)
 	if err != nil {
 		return nil, convertKnownErrors(err, bucket, key)
 	}
 	defer func() { err = errs.Combine(err, db.Close()) }()
 
-	obj, err := db.DeleteObject(ctx, bucket, key)
+	var precondition *metaclient.Precondition
+	if options != nil {
+		precondition = newPrecondition(options.Version, options.StreamID)
+	}
+
+	obj, err := db.DeleteObjectWithPrecondition(ctx, bucket, key, precondition)
 	if err != nil {
-		return nil, convertKnownErrors(err, bucket, key)
+		return convertObject(&obj), convertKnownErrors(err, bucket, key)
 	}
 	return convertObject(&obj), nil
 }
 
 // UploadObjectMetadataOptions contains additional options for updating object's metadata.
-// Reserved for future use.
 type UploadObjectMetadataOptions struct {
+	// Version and StreamID, when set, are the expected version and stream ID
+	// of the object. The update fails with ErrPreconditionFailed, when the
+	// object doesn't match them, so concurrent changes aren't overwritten.
+	Version  uint32
+	StreamID []byte
 }
 
 // UpdateObjectMetadata replaces the custom metadata for the object at the specific key with newMetadata.
@@ -129,7 +208,12 @@ func (project *Project) UpdateObjectMetadata(ctx context.Context, bucket, key st
 	}
 	defer func() { err = errs.Combine(err, db.Close()) }()
 
-	err = db.UpdateObjectMetadata(ctx, bucket, key, newMetadata.Clone())
+	var precondition *metaclient.Precondition
+	if options != nil {
+		precondition = newPrecondition(options.Version, options.StreamID)
+	}
+
+	err = db.UpdateObjectMetadataWithPrecondition(ctx, bucket, key, newMetadata.Clone(), precondition)
 	if err != nil {
 		return convertKnownErrors(err, bucket, key)
 	}
@@ -137,19 +221,47 @@ func (project *Project) UpdateObjectMetadata(ctx context.Context, bucket, key st
 	return nil
 }
 
+// newPrecondition returns the precondition of the expected version and stream
+// ID, or nil when neither is set.
+func newPrecondition(version uint32, streamID []byte) *metaclient.Precondition {
+	if version == 0 && len(streamID) == 0 {
+		return nil
+	}
+	return &metaclient.Precondition{
+		Version:  version,
+		StreamID: streamID,
+	}
+}
+
 // convertObject converts metainfo.Object to uplink.Object.
 func convertObject(obj *metaclient.Object) *Object {
 	if obj.Bucket.Name == "" { // zero object
 		return nil
 	}
 
-	return &Object{
-		Key: obj.Path,
+	object := &Object{
+		Key:      obj.Path,
+		Version:  obj.Version,
+		StreamID: obj.Stream.ID,
 		System: SystemMetadata{
 			Created:       obj.Created,
 			Expires:       obj.Expires,
 			ContentLength: obj.Size,
 		},
-		Custom: obj.Metadata,
+		Custom: customMetadata(obj.Metadata),
+	}
+
+	setSystemMetadata(&object.System, obj.Metadata)
+
+	return object
+}
+
+// setSystemMetadata sets the system metadata, which is stored in the object
+// metadata under the reserved keys.
+func setSystemMetadata(system *SystemMetadata, metadata map[string]string) {
+	if info, ok, err := compression.InfoFromMetadata(metadata); ok && err == nil {
+		system.Compression = Compression(info.Codec)
+		system.UncompressedLength = info.Size
 	}
+	system.SHA256, system.CRC32C = checksumsFromMetadata(metadata)
 }
@@ -5,6 +5,10 @@ package uplink
 
 import (
 	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/zeebo/errs"
 
@@ -24,11 +28,96 @@ type ListObjectsOptions struct {
 	Cursor string
 	// Recursive iterates the objects without collapsing prefixes.
 	Recursive bool
+	// Reverse iterates the objects in reverse order. The first item listed
+	// will be the one before the cursor, or the last one when Cursor is
+	// empty.
+	//
+	// Reverse isn't a cheap way to get the last objects. The satellite lists
+	// only forwards, so the first page of a new iterator lists every object
+	// from the start of the prefix up to the cursor, or all of them when
+	// Cursor is empty. It takes as many requests as there are pages of
+	// objects before the cursor. The following pages of the same iterator
+	// are listed from the positions found while listing the first one.
+	Reverse bool
 
 	// System includes SystemMetadata in the results.
 	System bool
 	// Custom includes CustomMetadata in the results.
 	Custom bool
+
+	// Filter, when not nil, lists only the objects matching it. The satellite
+	// can't filter the objects, so they are filtered while iterating.
+	Filter *ObjectFilter
+
+	// Pattern, when not empty, lists only the objects, whose keys relative
+	// to Prefix match the glob pattern, as in path.Match. The wildcards don't
+	// match slashes. The objects are listed recursively from the part of the
+	// pattern up to the last slash before the first wildcard, so the
+	// matching is evaluated on the decrypted keys under it.
+	Pattern string
+	// Delimiter, when not empty, collapses the keys at the first delimiter
+	// after Prefix into prefixes, instead of at the first slash. Prefix
+	// doesn't need to end with slash then. The keys are collapsed while
+	// iterating, so all objects under the last slash of Prefix are listed,
+	// which is efficient mainly for buckets with unencrypted keys.
+	Delimiter string
+}
+
+// ObjectFilter selects objects by their system metadata. The zero value of a
+// field doesn't filter. Prefixes aren't filtered.
+type ObjectFilter struct {
+	// CreatedBefore and CreatedAfter select the objects created before or
+	// after the time.
+	CreatedBefore time.Time
+	CreatedAfter  time.Time
+
+	// MinSize and MaxSize select the objects, whose content length is at
+	// least or at most the size. Zero MaxSize means no limit.
+	MinSize int64
+	MaxSize int64
+
+	// Expiration selects the objects with or without an expiration time.
+	Expiration ExpirationFilter
+	// ExpiresWithin selects the objects, which expire within the duration
+	// from the start of the listing.
+	ExpiresWithin time.Duration
+}
+
+// ExpirationFilter selects objects by whether they have an expiration time.
+type ExpirationFilter int
+
+const (
+	// AnyExpiration selects all objects.
+	AnyExpiration ExpirationFilter = iota
+	// WithExpiration selects the objects with an expiration time.
+	WithExpiration
+	// WithoutExpiration selects the objects without an expiration time.
+	WithoutExpiration
+)
+
+// matches returns whether the object matches the filter at the time now.
+func (filter *ObjectFilter) matches(item *metaclient.Object, now time.Time) bool {
+	if filter == nil || item.IsPrefix {
+		return true
+	}
+
+	switch {
+	case !filter.CreatedBefore.IsZero() && !item.Created.Before(filter.CreatedBefore):
+		return false
+	case !filter.CreatedAfter.IsZero() && !item.Created.After(filter.CreatedAfter):
+		return false
+	case item.Size < filter.MinSize:
+		return false
+	case filter.MaxSize > 0 && item.Size > filter.MaxSize:
+		return false
+	case filter.Expiration == WithExpiration && item.Expires.IsZero():
+		return false
+	case filter.Expiration == WithoutExpiration && !item.Expires.IsZero():
+		return false
+	case filter.ExpiresWithin > 0 && (item.Expires.IsZero() || item.Expires.After(now.Add(filter.ExpiresWithin))):
+		return false
+	}
+	return true
 }
 
 // ListObjects returns an iterator over the objects.
@@ -40,30 +129,118 @@ func (project *Project) ListObjects(ctx context.Context, bucket string, options
 		Direction: metaclient.After,
 	}
 
+	objects := ObjectIterator{
+		ctx:     ctx,
+		project: project,
+		bucket:  b,
+		now:     time.Now(),
+	}
+
 	if options != nil {
 		opts.Prefix = options.Prefix
 		opts.Cursor = options.Cursor
 		opts.Recursive = options.Recursive
+		if options.Pattern != "" || collapses(options) {
+			if _, err := path.Match(options.Pattern, ""); err != nil {
+				objects.err = packageError.Wrap(fmt.Errorf("invalid pattern %q: %w", options.Pattern, err))
+			}
+
+			// list recursively from the static part of the prefix and the
+			// pattern, which ends with slash.
+			static := options.Prefix + staticPattern(options.Pattern)
+			opts.Prefix = static[:strings.LastIndex(static, "/")+1]
+			opts.Recursive = true
+
+			// the cursor is relative to the listed prefix.
+			cursor := options.Prefix + options.Cursor
+			switch {
+			case options.Cursor == "":
+			case strings.HasPrefix(cursor, opts.Prefix):
+				opts.Cursor = cursor[len(opts.Prefix):]
+			case cursor < opts.Prefix:
+				opts.Cursor = ""
+			default:
+				objects.completed = true
+			}
+		}
+		if options.Reverse {
+			opts.Direction = metaclient.Before
+			opts.Pages = &metaclient.ListPages{}
+		}
 		opts.IncludeCustomMetadata = options.Custom
-		opts.IncludeSystemMetadata = options.System
+		// the system metadata is needed for filtering.
+		opts.IncludeSystemMetadata = options.System || options.Filter != nil
 	}
 
 	opts.Limit = testuplink.GetListLimit(ctx)
+	objects.options = opts
+
+	if options != nil {
+		objects.objOptions = *options
+	}
+
+	return &objects
+}
+
+// collapses returns whether the keys are collapsed at a custom delimiter.
+func collapses(options *ListObjectsOptions) bool {
+	return options.Delimiter != "" && options.Delimiter != "/" && !options.Recursive
+}
+
+// staticPattern returns the part of the glob pattern before its first
+// wildcard.
+func staticPattern(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+// ListObjectVersions returns an iterator over the latest version only of the
+// object at the specific key, including its system and custom metadata.
+//
+// The satellite keeps only the latest committed version of an object, so the
+// iterator returns at most one item and the older versions aren't listed.
+// Nothing is returned, when the object doesn't exist.
+func (project *Project) ListObjectVersions(ctx context.Context, bucket, key string) *ObjectIterator {
+	defer mon.Task()(&ctx)(nil)
 
 	objects := ObjectIterator{
 		ctx:     ctx,
 		project: project,
-		bucket:  b,
-		options: opts,
+		bucket:  metaclient.Bucket{Name: bucket, PathCipher: storj.EncAESGCM},
+		objOptions: ListObjectsOptions{
+			System: true,
+			Custom: true,
+		},
+		list:     &metaclient.ObjectList{},
+		position: -1,
+		now:      time.Now(),
 	}
 
-	if options != nil {
-		objects.objOptions = *options
+	object, err := objects.getObject(key)
+	switch {
+	case metaclient.ErrObjectNotFound.Has(err):
+	case err != nil:
+		objects.err = convertKnownErrors(err, bucket, key)
+	default:
+		objects.list.Items = append(objects.list.Items, object)
 	}
 
 	return &objects
 }
 
+// getObject returns the latest version of the object.
+func (objects *ObjectIterator) getObject(key string) (_ metaclient.Object, err error) {
+	db, err := objects.project.dialMetainfoDB(objects.ctx)
+	if err != nil {
+		return metaclient.Object{}, err
+	}
+	defer func() { err = errs.Combine(err, db.Close()) }()
+
+	return db.GetObject(objects.ctx, objects.bucket.Name, key)
+}
+
 // ObjectIterator is an iterator over a collection of objects or prefixes.
 type ObjectIterator struct {
 	ctx        context.Context
@@ -75,11 +252,72 @@ type ObjectIterator struct {
 	position   int
 	completed  bool
 	err        error
+	now        time.Time
+
+	// prefix is the current prefix collapsed at the custom delimiter and
+	// prefixes are the ones already returned.
+	prefix   *metaclient.Object
+	prefixes map[string]bool
 }
 
 // Next prepares next Object for reading.
 // It returns false if the end of the iteration is reached and there are no more objects, or if there is an error.
 func (objects *ObjectIterator) Next() bool {
+	objects.prefix = nil
+	if objects.completed {
+		return false
+	}
+	for objects.next() {
+		if objects.accept(objects.item()) {
+			return true
+		}
+	}
+	return false
+}
+
+// accept returns whether the listed item should be returned. Items collapsed
+// into a custom delimited prefix are returned as the prefix, when it's
+// returned for the first time.
+func (objects *ObjectIterator) accept(item *metaclient.Object) bool {
+	options := &objects.objOptions
+
+	key := objects.options.Prefix + item.Path
+	if !strings.HasPrefix(key, options.Prefix) {
+		return false
+	}
+	relative := key[len(options.Prefix):]
+
+	if options.Pattern != "" {
+		if match, _ := path.Match(options.Pattern, relative); !match {
+			return false
+		}
+	}
+
+	if collapses(options) {
+		if i := strings.Index(relative, options.Delimiter); i >= 0 {
+			// the encrypted keys collapsed into a prefix aren't listed one
+			// after another, so the returned prefixes are kept.
+			prefix := options.Prefix + relative[:i+len(options.Delimiter)]
+			if objects.prefixes[prefix] {
+				return false
+			}
+			if objects.prefixes == nil {
+				objects.prefixes = map[string]bool{}
+			}
+			objects.prefixes[prefix] = true
+			objects.prefix = &metaclient.Object{
+				Path:     prefix[len(objects.options.Prefix):],
+				IsPrefix: true,
+			}
+			return true
+		}
+	}
+
+	return options.Filter.matches(item, objects.now)
+}
+
+// next moves to the next listed object, whether it matches the filter or not.
+func (objects *ObjectIterator) next() bool {
 	if objects.err != nil {
 		objects.completed = true
 		return false
@@ -155,6 +393,12 @@ func (objects *ObjectIterator) Item() *Object {
 		Key:      key,
 		IsPrefix: item.IsPrefix,
 	}
+	if !item.IsPrefix {
+		obj.Version = item.Version
+		// the satellite returns the stream IDs of the listed objects only
+		// for the pending ones.
+		obj.StreamID = item.Stream.ID
+	}
 
 	// TODO: Make this filtering on the satellite
 	if objects.objOptions.System {
@@ -163,11 +407,13 @@ func (objects *ObjectIterator) Item() *Object {
 			Expires:       item.Expires,
 			ContentLength: item.Size,
 		}
+		// the metadata is listed only together with the custom metadata.
+		setSystemMetadata(&obj.System, item.Metadata)
 	}
 
 	// TODO: Make this filtering on the satellite
 	if objects.objOptions.Custom {
-		obj.Custom = item.Metadata
+		obj.Custom = customMetadata(item.Metadata)
 	}
 
 	return &obj
@@ -190,5 +436,8 @@ func (objects *ObjectIterator) item() *metaclient.Object {
 		return nil
 	}
 
+	if objects.prefix != nil {
+		return objects.prefix
+	}
 	return &objects.list.Items[objects.position]
 }
@@ -0,0 +1,42 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package compression implements the seekable format of compressed objects.
+//
+// The plain data is split into frames of a fixed size. Each frame is compressed
+// independently and the compressed frames are concatenated. The data is followed
+// by an index with the compressed size of every frame, which allows to
+// decompress a range of the data without reading it from the start.
+package compression
+
+import (
+	"github.com/zeebo/errs"
+)
+
+// Error is the errs class of compression errors.
+var Error = errs.Class("compression")
+
+// Codec is a compression algorithm.
+type Codec string
+
+// Gzip compresses the frames with gzip.
+const Gzip Codec = "gzip"
+
+// DefaultFrameSize is the default plain size of a frame.
+const DefaultFrameSize = 1 << 20
+
+// Valid returns whether the codec is supported.
+func (codec Codec) Valid() bool {
+	return codec == Gzip
+}
+
+// Info describes compressed data.
+type Info struct {
+	Codec Codec
+	// FrameSize is the plain size of all frames, except the last one.
+	FrameSize int64
+	// Size is the plain size of the data.
+	Size int64
+	// IndexSize is the size of the index at the end of the compressed data.
+	IndexSize int64
+}
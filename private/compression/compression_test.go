@@ -0,0 +1,106 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package compression_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/testrand"
+	"storj.io/uplink/private/compression"
+)
+
+func TestCompression(t *testing.T) {
+	const frameSize = 1000
+
+	for _, size := range []int{0, 1, frameSize - 1, frameSize, frameSize + 1, 10*frameSize + 123} {
+		// repeat a small random pattern, so that the data is compressible
+		data := bytes.Repeat(testrand.BytesInt(10), size/10+1)[:size]
+
+		var compressed bytes.Buffer
+		writer, err := compression.NewWriter(&compressed, compression.Gzip, frameSize)
+		require.NoError(t, err)
+
+		// write in pieces, which don't align with the frames
+		for rest := data; len(rest) > 0; {
+			n := 333
+			if n > len(rest) {
+				n = len(rest)
+			}
+			_, err := writer.Write(rest[:n])
+			require.NoError(t, err)
+			rest = rest[n:]
+		}
+		require.NoError(t, writer.Close())
+
+		info := writer.Info()
+		require.Equal(t, compression.Gzip, info.Codec)
+		require.EqualValues(t, size, info.Size)
+		require.EqualValues(t, frameSize, info.FrameSize)
+
+		stored := compressed.Bytes()
+		dataSize := int64(len(stored)) - info.IndexSize
+
+		frames, err := compression.ParseIndex(stored[dataSize:])
+		require.NoError(t, err)
+		require.Len(t, frames, (size+frameSize-1)/frameSize)
+
+		for _, r := range []struct{ offset, length int64 }{
+			{0, int64(size)},
+			{0, 0},
+			{int64(size) / 3, int64(size) / 2},
+			{int64(size) / 2, int64(size) - int64(size)/2},
+		} {
+			start, limit, skip, err := compression.FrameRange(info, frames, r.offset, r.length)
+			require.NoError(t, err)
+			require.True(t, limit <= dataSize)
+
+			reader, err := compression.NewReader(ioutil.NopCloser(bytes.NewReader(stored[start:limit])), info.Codec, skip, r.length)
+			require.NoError(t, err)
+
+			plain, err := ioutil.ReadAll(reader)
+			require.NoError(t, err)
+			require.NoError(t, reader.Close())
+			require.Equal(t, data[r.offset:r.offset+r.length], plain)
+		}
+	}
+}
+
+func TestCompressionInvalid(t *testing.T) {
+	_, err := compression.NewWriter(ioutil.Discard, "unknown", 0)
+	require.Error(t, err)
+
+	info := compression.Info{Codec: compression.Gzip, FrameSize: 10, Size: 100}
+	_, _, _, err = compression.FrameRange(info, make([]int64, 10), 90, 20)
+	require.Error(t, err)
+	_, _, _, err = compression.FrameRange(info, make([]int64, 5), 90, 10)
+	require.Error(t, err)
+
+	_, err = compression.ParseIndex([]byte{0xff})
+	require.Error(t, err)
+}
+
+func TestMetadata(t *testing.T) {
+	info := compression.Info{
+		Codec:     compression.Gzip,
+		FrameSize: compression.DefaultFrameSize,
+		Size:      12345,
+		IndexSize: 3,
+	}
+
+	metadata := map[string]string{"custom": "value"}
+	info.AddToMetadata(metadata)
+
+	parsed, ok, err := compression.InfoFromMetadata(metadata)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, info, parsed)
+
+	_, ok, err = compression.InfoFromMetadata(map[string]string{"custom": "value"})
+	require.NoError(t, err)
+	require.False(t, ok)
+}
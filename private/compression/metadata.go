@@ -0,0 +1,56 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package compression
+
+import (
+	"strconv"
+
+	"storj.io/uplink/private/metaclient"
+)
+
+// keys of the compression information in the object metadata.
+const (
+	codecKey     = metaclient.SystemMetadataPrefix + "compression"
+	frameSizeKey = metaclient.SystemMetadataPrefix + "compression-frame-size"
+	sizeKey      = metaclient.SystemMetadataPrefix + "compression-size"
+	indexSizeKey = metaclient.SystemMetadataPrefix + "compression-index-size"
+)
+
+// AddToMetadata stores the information in the object metadata.
+func (info Info) AddToMetadata(metadata map[string]string) {
+	metadata[codecKey] = string(info.Codec)
+	metadata[frameSizeKey] = strconv.FormatInt(info.FrameSize, 10)
+	metadata[sizeKey] = strconv.FormatInt(info.Size, 10)
+	metadata[indexSizeKey] = strconv.FormatInt(info.IndexSize, 10)
+}
+
+// InfoFromMetadata returns the compression information stored in the object
+// metadata. ok is false when the object isn't compressed.
+func InfoFromMetadata(metadata map[string]string) (info Info, ok bool, err error) {
+	codec, ok := metadata[codecKey]
+	if !ok {
+		return Info{}, false, nil
+	}
+
+	info.Codec = Codec(codec)
+	if !info.Codec.Valid() {
+		return Info{}, true, Error.New("unsupported codec %q", codec)
+	}
+
+	for key, value := range map[string]*int64{
+		frameSizeKey: &info.FrameSize,
+		sizeKey:      &info.Size,
+		indexSizeKey: &info.IndexSize,
+	} {
+		*value, err = strconv.ParseInt(metadata[key], 10, 64)
+		if err != nil || *value < 0 {
+			return Info{}, true, Error.New("invalid metadata %q", key[len(metaclient.SystemMetadataPrefix):])
+		}
+	}
+	if info.FrameSize == 0 {
+		return Info{}, true, Error.New("invalid frame size")
+	}
+
+	return info, true, nil
+}
@@ -0,0 +1,113 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package compression
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// ParseIndex parses the index at the end of the compressed data and returns
+// the compressed sizes of the frames.
+func ParseIndex(data []byte) ([]int64, error) {
+	var frames []int64
+	for len(data) > 0 {
+		size, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, Error.New("invalid index")
+		}
+		frames = append(frames, int64(size))
+		data = data[n:]
+	}
+	return frames, nil
+}
+
+// FrameRange returns the range of the compressed data, which has to be
+// decompressed for reading the plain range from offset to offset+length.
+// skip is the number of plain bytes that need to be discarded at the start of
+// the decompressed range.
+func FrameRange(info Info, frames []int64, offset, length int64) (start, limit, skip int64, err error) {
+	if offset < 0 || length < 0 || offset+length > info.Size {
+		return 0, 0, 0, Error.New("invalid range %d:%d for size %d", offset, length, info.Size)
+	}
+	if length == 0 {
+		return 0, 0, 0, nil
+	}
+
+	first := offset / info.FrameSize
+	last := (offset + length - 1) / info.FrameSize
+	if last >= int64(len(frames)) {
+		return 0, 0, 0, Error.New("index contains %d frames, frame %d requested", len(frames), last)
+	}
+
+	for i, size := range frames[:last+1] {
+		if int64(i) < first {
+			start += size
+		}
+		limit += size
+	}
+
+	return start, limit, offset - first*info.FrameSize, nil
+}
+
+// Reader decompresses a range of compressed frames.
+type Reader struct {
+	source io.ReadCloser
+	codec  Codec
+	skip   int64
+	length int64
+
+	reader io.Reader
+	gzip   *gzip.Reader
+}
+
+// NewReader returns a reader, which decompresses frames read from source, skips
+// the first skip bytes and returns the next length bytes.
+func NewReader(source io.ReadCloser, codec Codec, skip, length int64) (*Reader, error) {
+	if !codec.Valid() {
+		return nil, Error.New("unsupported codec %q", codec)
+	}
+	return &Reader{
+		source: source,
+		codec:  codec,
+		skip:   skip,
+		length: length,
+	}, nil
+}
+
+// Read reads decompressed data.
+func (reader *Reader) Read(p []byte) (n int, err error) {
+	if reader.length <= 0 {
+		return 0, io.EOF
+	}
+
+	if reader.reader == nil {
+		reader.gzip, err = gzip.NewReader(reader.source)
+		if err != nil {
+			return 0, Error.Wrap(err)
+		}
+		if _, err := io.CopyN(ioutil.Discard, reader.gzip, reader.skip); err != nil {
+			return 0, Error.Wrap(err)
+		}
+		reader.reader = io.LimitReader(reader.gzip, reader.length)
+	}
+
+	n, err = reader.reader.Read(p)
+	reader.length -= int64(n)
+	if errors.Is(err, io.EOF) && reader.length > 0 {
+		return n, Error.Wrap(io.ErrUnexpectedEOF)
+	}
+	return n, err
+}
+
+// Close closes the source.
+func (reader *Reader) Close() error {
+	if reader.gzip != nil {
+		_ = reader.gzip.Close()
+	}
+	return reader.source.Close()
+}
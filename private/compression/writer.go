@@ -0,0 +1,133 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package compression
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+)
+
+// Writer compresses the data written to it into frames.
+type Writer struct {
+	out    countingWriter
+	gzip   *gzip.Writer
+	info   Info
+	buffer []byte
+	frames []int64
+	closed bool
+}
+
+// NewWriter returns a writer which writes the compressed data to w.
+func NewWriter(w io.Writer, codec Codec, frameSize int64) (*Writer, error) {
+	if !codec.Valid() {
+		return nil, Error.New("unsupported codec %q", codec)
+	}
+	if frameSize <= 0 {
+		frameSize = DefaultFrameSize
+	}
+
+	writer := &Writer{
+		out: countingWriter{writer: w},
+		info: Info{
+			Codec:     codec,
+			FrameSize: frameSize,
+		},
+	}
+	writer.gzip = gzip.NewWriter(&writer.out)
+	return writer, nil
+}
+
+// Write compresses p.
+func (writer *Writer) Write(p []byte) (n int, err error) {
+	if writer.closed {
+		return 0, Error.New("already closed")
+	}
+
+	for len(p) > 0 {
+		free := int(writer.info.FrameSize) - len(writer.buffer)
+		if free > len(p) {
+			free = len(p)
+		}
+		writer.buffer = append(writer.buffer, p[:free]...)
+		p = p[free:]
+		n += free
+
+		if int64(len(writer.buffer)) == writer.info.FrameSize {
+			if err := writer.flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// Close writes the last frame and the index. It doesn't close the
+// underlying writer.
+func (writer *Writer) Close() error {
+	if writer.closed {
+		return Error.New("already closed")
+	}
+	writer.closed = true
+
+	if len(writer.buffer) > 0 {
+		if err := writer.flush(); err != nil {
+			return err
+		}
+	}
+
+	index := make([]byte, 0, len(writer.frames)*binary.MaxVarintLen32)
+	for _, frame := range writer.frames {
+		index = appendUvarint(index, uint64(frame))
+	}
+	if _, err := writer.out.Write(index); err != nil {
+		return Error.Wrap(err)
+	}
+	writer.info.IndexSize = int64(len(index))
+
+	return nil
+}
+
+// Info returns information about the compressed data. It's complete only after
+// the writer has been closed.
+func (writer *Writer) Info() Info {
+	return writer.info
+}
+
+// flush compresses the buffered data as a single frame.
+func (writer *Writer) flush() error {
+	start := writer.out.written
+
+	writer.gzip.Reset(&writer.out)
+	if _, err := writer.gzip.Write(writer.buffer); err != nil {
+		return Error.Wrap(err)
+	}
+	if err := writer.gzip.Close(); err != nil {
+		return Error.Wrap(err)
+	}
+
+	writer.frames = append(writer.frames, writer.out.written-start)
+	writer.info.Size += int64(len(writer.buffer))
+	writer.buffer = writer.buffer[:0]
+	return nil
+}
+
+func appendUvarint(data []byte, value uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], value)
+	return append(data, buf[:n]...)
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	writer  io.Writer
+	written int64
+}
+
+func (counter *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = counter.writer.Write(p)
+	counter.written += int64(n)
+	return n, err
+}
@@ -0,0 +1,101 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package corruption collects the pieces, which returned corrupted data to
+// downloads.
+package corruption
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"storj.io/common/storj"
+)
+
+// Piece identifies a piece of a segment stored on a storage node.
+type Piece struct {
+	Segment  storj.SegmentPosition
+	PieceNum int
+	NodeID   storj.NodeID
+}
+
+// Report collects the corrupted pieces of a download.
+//
+// The methods are safe for concurrent use and do nothing on a nil Report.
+type Report struct {
+	mu     sync.Mutex
+	pieces map[Piece]struct{}
+}
+
+// NewReport returns a new report.
+func NewReport() *Report {
+	return &Report{
+		pieces: map[Piece]struct{}{},
+	}
+}
+
+// Add adds the corrupted piece. Adding a piece again does nothing.
+func (report *Report) Add(piece Piece) {
+	if report == nil {
+		return
+	}
+	report.mu.Lock()
+	report.pieces[piece] = struct{}{}
+	report.mu.Unlock()
+}
+
+// Pieces returns the corrupted pieces sorted by their segment and piece
+// number.
+func (report *Report) Pieces() []Piece {
+	if report == nil {
+		return nil
+	}
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	pieces := make([]Piece, 0, len(report.pieces))
+	for piece := range report.pieces {
+		pieces = append(pieces, piece)
+	}
+	sort.Slice(pieces, func(i, k int) bool {
+		a, b := pieces[i], pieces[k]
+		switch {
+		case a.Segment.PartNumber != b.Segment.PartNumber:
+			return a.Segment.PartNumber < b.Segment.PartNumber
+		case a.Segment.Index != b.Segment.Index:
+			return a.Segment.Index < b.Segment.Index
+		default:
+			return a.PieceNum < b.PieceNum
+		}
+	})
+	return pieces
+}
+
+type reportKey struct{}
+
+// WithReport returns a context, which collects the corrupted pieces of the
+// downloads done with it in report.
+func WithReport(ctx context.Context, report *Report) context.Context {
+	return context.WithValue(ctx, reportKey{}, report)
+}
+
+// ReportFromContext returns the report of the context or nil.
+func ReportFromContext(ctx context.Context) *Report {
+	report, _ := ctx.Value(reportKey{}).(*Report)
+	return report
+}
+
+type segmentKey struct{}
+
+// WithSegment returns a context, whose downloaded pieces belong to the segment
+// at position.
+func WithSegment(ctx context.Context, position storj.SegmentPosition) context.Context {
+	return context.WithValue(ctx, segmentKey{}, position)
+}
+
+// SegmentFromContext returns the segment position set with WithSegment.
+func SegmentFromContext(ctx context.Context) storj.SegmentPosition {
+	position, _ := ctx.Value(segmentKey{}).(storj.SegmentPosition)
+	return position
+}
@@ -11,6 +11,7 @@ import (
 	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/spacemonkeygo/monkit/v3"
@@ -23,8 +24,12 @@ import (
 	"storj.io/common/ranger"
 	"storj.io/common/rpc"
 	"storj.io/common/storj"
+	"storj.io/uplink/private/corruption"
 	"storj.io/uplink/private/eestream"
+	"storj.io/uplink/private/membudget"
 	"storj.io/uplink/private/piecestore"
+	"storj.io/uplink/private/progress"
+	"storj.io/uplink/private/ratelimit"
 )
 
 var mon = monkit.Package()
@@ -34,6 +39,11 @@ type Client interface {
 	PutSingleResult(ctx context.Context, limits []*pb.AddressedOrderLimit, privateKey storj.PiecePrivateKey, rs eestream.RedundancyStrategy, data io.Reader) (results []*pb.SegmentPieceUploadResult, err error)
 	Get(ctx context.Context, limits []*pb.AddressedOrderLimit, privateKey storj.PiecePrivateKey, es eestream.ErasureScheme, size int64) (ranger.Ranger, error)
 	WithForceErrorDetection(force bool) Client
+	WithRateLimits(upload, download *ratelimit.Limiter) Client
+	WithSpill(config eestream.SpillConfig) Client
+	WithMemoryBudget(budget *membudget.Budget) Client
+	WithLongTail(config *eestream.LongTail) Client
+	WithUploadLongTail(config eestream.UploadLongTail) Client
 	// PutPiece is not intended to be used by normal uplinks directly, but is exported to support storagenode graceful exit transfers.
 	PutPiece(ctx, parent context.Context, limit *pb.AddressedOrderLimit, privateKey storj.PiecePrivateKey, data io.ReadCloser) (hash *pb.PieceHash, id *identity.PeerIdentity, err error)
 }
@@ -44,6 +54,12 @@ type ecClient struct {
 	dialer              rpc.Dialer
 	memoryLimit         int
 	forceErrorDetection bool
+	uploadRate          *ratelimit.Limiter
+	downloadRate        *ratelimit.Limiter
+	spill               eestream.SpillConfig
+	budget              *membudget.Budget
+	longTail            *eestream.LongTail
+	uploadLongTail      eestream.UploadLongTail
 }
 
 // New creates a client from the given dialer and max buffer memory.
@@ -59,12 +75,76 @@ func (ec *ecClient) WithForceErrorDetection(force bool) Client {
 	return ec
 }
 
+// WithRateLimits limits the bandwidth of all uploads and downloads. The limits
+// can be overridden for a single transfer with ratelimit.WithLimiter.
+func (ec *ecClient) WithRateLimits(upload, download *ratelimit.Limiter) Client {
+	ec.uploadRate = upload
+	ec.downloadRate = download
+	return ec
+}
+
+// WithSpill makes the buffers of the erasure encoding and decoding spill to
+// temporary files. It can be overridden for a single transfer with
+// eestream.WithSpill.
+func (ec *ecClient) WithSpill(config eestream.SpillConfig) Client {
+	ec.spill = config
+	return ec
+}
+
+// WithMemoryBudget makes all segment uploads and downloads reserve their
+// memory from budget before they start.
+func (ec *ecClient) WithMemoryBudget(budget *membudget.Budget) Client {
+	ec.budget = budget
+	return ec
+}
+
+// WithLongTail configures how the pieces of all segment downloads are
+// downloaded. Nil starts the downloads of all pieces at once. It can be
+// overridden for a single download with eestream.WithLongTail.
+func (ec *ecClient) WithLongTail(config *eestream.LongTail) Client {
+	ec.longTail = config
+	return ec
+}
+
+// WithUploadLongTail configures when the piece uploads of all segment uploads
+// are finished. It can be overridden for a single upload with
+// eestream.WithUploadLongTail.
+func (ec *ecClient) WithUploadLongTail(config eestream.UploadLongTail) Client {
+	ec.uploadLongTail = config
+	return ec
+}
+
+// withSpill adds the spill configuration of the client to ctx, unless it's
+// already configured there.
+func (ec *ecClient) withSpill(ctx context.Context) context.Context {
+	if _, ok := eestream.SpillFromContext(ctx); ok || !ec.spill.Enabled() {
+		return ctx
+	}
+	return eestream.WithSpill(ctx, ec.spill)
+}
+
+// withLongTail adds the long tail configuration of the client to ctx, unless
+// it's already configured there.
+func (ec *ecClient) withLongTail(ctx context.Context) context.Context {
+	if _, ok := eestream.LongTailFromContext(ctx); ok || ec.longTail == nil {
+		return ctx
+	}
+	return eestream.WithLongTail(ctx, *ec.longTail)
+}
+
 func (ec *ecClient) dialPiecestore(ctx context.Context, n storj.NodeURL) (*piecestore.Client, error) {
-	return piecestore.Dial(ctx, ec.dialer, n, piecestore.DefaultConfig)
+	config := piecestore.DefaultConfig
+	config.UploadRate = ec.uploadRate
+	config.DownloadRate = ec.downloadRate
+	if limiter, ok := ratelimit.FromContext(ctx); ok {
+		config.UploadRate = limiter
+		config.DownloadRate = limiter
+	}
+	return piecestore.Dial(ctx, ec.dialer, n, config)
 }
 
 func (ec *ecClient) PutSingleResult(ctx context.Context, limits []*pb.AddressedOrderLimit, privateKey storj.PiecePrivateKey, rs eestream.RedundancyStrategy, data io.Reader) (results []*pb.SegmentPieceUploadResult, err error) {
-	successfulNodes, successfulHashes, err := ec.put(ctx, limits, privateKey, rs, data, time.Time{})
+	successfulNodes, successfulHashes, timedOut, err := ec.put(ctx, limits, privateKey, rs, data, time.Time{})
 	if err != nil {
 		return nil, err
 	}
@@ -82,14 +162,27 @@ func (ec *ecClient) PutSingleResult(ctx context.Context, limits []*pb.AddressedO
 		})
 	}
 
-	if l := len(uploadResults); l < rs.OptimalThreshold() {
-		return nil, Error.New("uploaded results (%d) are below the optimal threshold (%d)", l, rs.OptimalThreshold())
+	if err := checkUploadResults(len(uploadResults), rs, timedOut); err != nil {
+		return nil, err
 	}
 
 	return uploadResults, nil
 }
 
-func (ec *ecClient) put(ctx context.Context, limits []*pb.AddressedOrderLimit, privateKey storj.PiecePrivateKey, rs eestream.RedundancyStrategy, data io.Reader, expiration time.Time) (successfulNodes []*pb.Node, successfulHashes []*pb.PieceHash, err error) {
+// checkUploadResults returns an error, when the number of uploaded pieces
+// isn't enough. Only after the upload long tail timeout fired, more pieces
+// than the repair threshold are enough instead of the optimal threshold.
+func checkUploadResults(uploaded int, rs eestream.RedundancyStrategy, timedOut bool) error {
+	switch {
+	case timedOut && uploaded <= rs.RepairThreshold():
+		return Error.New("uploaded results (%d) are not above the repair threshold (%d)", uploaded, rs.RepairThreshold())
+	case !timedOut && uploaded < rs.OptimalThreshold():
+		return Error.New("uploaded results (%d) are below the optimal threshold (%d)", uploaded, rs.OptimalThreshold())
+	}
+	return nil
+}
+
+func (ec *ecClient) put(ctx context.Context, limits []*pb.AddressedOrderLimit, privateKey storj.PiecePrivateKey, rs eestream.RedundancyStrategy, data io.Reader, expiration time.Time) (successfulNodes []*pb.Node, successfulHashes []*pb.PieceHash, timedOut bool, err error) {
 	defer mon.Task()(&ctx,
 		"erasure:"+strconv.Itoa(rs.ErasureShareSize()),
 		"stripe:"+strconv.Itoa(rs.StripeSize()),
@@ -99,22 +192,32 @@ func (ec *ecClient) put(ctx context.Context, limits []*pb.AddressedOrderLimit, p
 
 	pieceCount := len(limits)
 	if pieceCount != rs.TotalCount() {
-		return nil, nil, Error.New("size of limits slice (%d) does not match total count (%d) of erasure scheme", pieceCount, rs.TotalCount())
+		return nil, nil, false, Error.New("size of limits slice (%d) does not match total count (%d) of erasure scheme", pieceCount, rs.TotalCount())
 	}
 
 	nonNilLimits := nonNilCount(limits)
 	if nonNilLimits <= rs.RepairThreshold() && nonNilLimits < rs.OptimalThreshold() {
-		return nil, nil, Error.New("number of non-nil limits (%d) is less than or equal to the repair threshold (%d) of erasure scheme", nonNilLimits, rs.RepairThreshold())
+		return nil, nil, false, Error.New("number of non-nil limits (%d) is less than or equal to the repair threshold (%d) of erasure scheme", nonNilLimits, rs.RepairThreshold())
 	}
 
 	if !unique(limits) {
-		return nil, nil, Error.New("duplicated nodes are not allowed")
+		return nil, nil, false, Error.New("duplicated nodes are not allowed")
+	}
+
+	// the encoded data of the segment may be buffered in memory, so the
+	// upload reserves its maximum size, unless the caller has done it.
+	if !membudget.IsReserved(ctx) {
+		release, err := ec.budget.Acquire(ctx, uploadMemory(limits, rs))
+		if err != nil {
+			return nil, nil, false, Error.Wrap(err)
+		}
+		defer release()
 	}
 
 	padded := encryption.PadReader(ioutil.NopCloser(data), rs.StripeSize())
-	readers, err := eestream.EncodeReader2(ctx, padded, rs)
+	readers, err := eestream.EncodeReader2(ec.withSpill(ctx), padded, rs)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, false, err
 	}
 
 	type info struct {
@@ -127,6 +230,13 @@ func (ec *ecClient) put(ctx context.Context, limits []*pb.AddressedOrderLimit, p
 	piecesCtx, piecesCancel := context.WithCancel(ctx)
 	defer piecesCancel()
 
+	longTail := ec.uploadLongTail
+	if config, ok := eestream.UploadLongTailFromContext(ctx); ok {
+		longTail = config
+	}
+	var longTailTimer *time.Timer
+	var longTailTimedOut int32
+
 	for i, addressedLimit := range limits {
 		go func(i int, addressedLimit *pb.AddressedOrderLimit) {
 			hash, _, err := ec.PutPiece(piecesCtx, ctx, addressedLimit, privateKey, readers[i])
@@ -160,11 +270,22 @@ func (ec *ecClient) put(ctx context.Context, limits []*pb.AddressedOrderLimit, p
 		successfulHashes[info.i] = info.hash
 
 		successfulCount++
-		if int(successfulCount) >= rs.OptimalThreshold() {
+		if longTail.Timeout > 0 && longTailTimer == nil && int(successfulCount) > rs.RepairThreshold() {
+			// cancelling remaining uploads after the timeout
+			longTailTimer = time.AfterFunc(longTail.Timeout, func() {
+				atomic.StoreInt32(&longTailTimedOut, 1)
+				piecesCancel()
+			})
+		}
+		if !longTail.WaitForAll && int(successfulCount) >= rs.OptimalThreshold() {
 			// cancelling remaining uploads
 			piecesCancel()
 		}
 	}
+	if longTailTimer != nil {
+		longTailTimer.Stop()
+	}
+	timedOut = atomic.LoadInt32(&longTailTimedOut) != 0
 
 	defer func() {
 		select {
@@ -181,16 +302,21 @@ func (ec *ecClient) put(ctx context.Context, limits []*pb.AddressedOrderLimit, p
 	mon.IntVal("put_segment_pieces_successful").Observe(int64(successfulCount))
 	mon.IntVal("put_segment_pieces_failed").Observe(int64(failureCount))
 	mon.IntVal("put_segment_pieces_canceled").Observe(int64(cancellationCount))
+	if timedOut {
+		mon.Event("put_segment_long_tail_timeout")
+	}
+	progress.TrackerFromContext(ctx).AddPieces(int64(successfulCount), int64(failureCount), int64(cancellationCount))
 
 	if int(successfulCount) <= rs.RepairThreshold() && int(successfulCount) < rs.OptimalThreshold() {
-		return nil, nil, Error.New("successful puts (%d) less than or equal to repair threshold (%d)", successfulCount, rs.RepairThreshold())
+		return nil, nil, false, Error.New("successful puts (%d) less than or equal to repair threshold (%d)", successfulCount, rs.RepairThreshold())
 	}
 
-	if int(successfulCount) < rs.OptimalThreshold() {
-		return nil, nil, Error.New("successful puts (%d) less than success threshold (%d)", successfulCount, rs.OptimalThreshold())
+	// after the long tail timeout more pieces than the repair threshold are enough.
+	if !timedOut && int(successfulCount) < rs.OptimalThreshold() {
+		return nil, nil, false, Error.New("successful puts (%d) less than success threshold (%d)", successfulCount, rs.OptimalThreshold())
 	}
 
-	return successfulNodes, successfulHashes, nil
+	return successfulNodes, successfulHashes, timedOut, nil
 }
 
 func (ec *ecClient) PutPiece(ctx, parent context.Context, limit *pb.AddressedOrderLimit, privateKey storj.PiecePrivateKey, data io.ReadCloser) (hash *pb.PieceHash, peerID *identity.PeerIdentity, err error) {
@@ -261,6 +387,9 @@ func (ec *ecClient) Get(ctx context.Context, limits []*pb.AddressedOrderLimit, p
 		return nil, Error.New("number of non-nil limits (%d) is less than required count (%d) of erasure scheme", nonNilCount(limits), es.RequiredCount())
 	}
 
+	failed := FailedNodesFromContext(ctx)
+	limits = failed.exclude(limits, es.RequiredCount())
+
 	paddedSize := calcPadded(size, es.StripeSize())
 	pieceSize := paddedSize / int64(es.RequiredCount())
 
@@ -275,6 +404,7 @@ func (ec *ecClient) Get(ctx context.Context, limits []*pb.AddressedOrderLimit, p
 			limit:          addressedLimit,
 			privateKey:     privateKey,
 			size:           pieceSize,
+			failed:         failed,
 		}
 	}
 
@@ -283,10 +413,74 @@ func (ec *ecClient) Get(ctx context.Context, limits []*pb.AddressedOrderLimit, p
 		return nil, Error.Wrap(err)
 	}
 
+	rr = &decodeRanger{
+		Ranger: rr,
+		ec:     ec,
+		limits: limits,
+		memory: eestream.DecodeMemory(es, len(rrs), ec.memoryLimit, ec.spill),
+	}
+
 	ranger, err := encryption.Unpad(rr, int(paddedSize-size))
 	return ranger, Error.Wrap(err)
 }
 
+// decodeRanger configures the decoding of its ranges with the spill and long
+// tail configuration and the memory budget of the client. The corrupted
+// pieces are reported to the corruption report of the context.
+type decodeRanger struct {
+	ranger.Ranger
+	ec     *ecClient
+	limits []*pb.AddressedOrderLimit
+	memory int64
+}
+
+func (rr *decodeRanger) Range(ctx context.Context, offset, length int64) (_ io.ReadCloser, err error) {
+	release, err := rr.ec.budget.Acquire(ctx, rr.memory)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	if report := corruption.ReportFromContext(ctx); report != nil {
+		segment := corruption.SegmentFromContext(ctx)
+		ctx = eestream.WithVerification(ctx, func(piece int) {
+			report.Add(corruption.Piece{
+				Segment:  segment,
+				PieceNum: piece,
+				NodeID:   rr.limits[piece].GetLimit().StorageNodeId,
+			})
+		})
+	}
+
+	reader, err := rr.Ranger.Range(rr.ec.withLongTail(rr.ec.withSpill(ctx)), offset, length)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &releaseReader{ReadCloser: reader, release: release}, nil
+}
+
+// releaseReader releases the reserved memory when it's closed.
+type releaseReader struct {
+	io.ReadCloser
+	release func()
+}
+
+func (reader *releaseReader) Close() error {
+	defer reader.release()
+	return reader.ReadCloser.Close()
+}
+
+// uploadMemory returns the maximum size of the encoded data of a segment
+// uploaded with limits.
+func uploadMemory(limits []*pb.AddressedOrderLimit, rs eestream.RedundancyStrategy) int64 {
+	for _, limit := range limits {
+		if limit != nil {
+			return limit.GetLimit().Limit * int64(rs.RequiredCount())
+		}
+	}
+	return 0
+}
+
 func unique(limits []*pb.AddressedOrderLimit) bool {
 	if len(limits) < 2 {
 		return true
@@ -323,6 +517,7 @@ type lazyPieceRanger struct {
 	limit          *pb.AddressedOrderLimit
 	privateKey     storj.PiecePrivateKey
 	size           int64
+	failed         *FailedNodes
 }
 
 // Size implements Ranger.Size.
@@ -365,13 +560,28 @@ func (lr *lazyPieceReader) Read(data []byte) (_ int, err error) {
 	if lr.Downloader == nil {
 		client, downloader, err := lr.ranger.dial(lr.ctx, lr.offset, lr.length)
 		if err != nil {
+			lr.fail(err)
 			return 0, err
 		}
 		lr.Downloader = downloader
 		lr.client = client
 	}
 
-	return lr.Downloader.Read(data)
+	n, err := lr.Downloader.Read(data)
+	progress.TrackerFromContext(lr.ctx).AddNodeBytes(lr.ranger.limit.GetLimit().StorageNodeId, int64(n))
+	if err != nil && !errors.Is(err, io.EOF) {
+		lr.fail(err)
+	}
+	return n, err
+}
+
+// fail records the node of the piece as failed, unless the download has
+// been canceled.
+func (lr *lazyPieceReader) fail(err error) {
+	if lr.ctx.Err() != nil || errs2.IsCanceled(err) {
+		return
+	}
+	lr.ranger.failed.Add(lr.ranger.limit.GetLimit().StorageNodeId)
 }
 
 func (lr *lazyPieceRanger) dial(ctx context.Context, offset, length int64) (_ *piecestore.Client, _ piecestore.Downloader, err error) {
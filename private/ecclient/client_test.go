@@ -8,9 +8,12 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"storj.io/common/pb"
+	"storj.io/common/storj"
 	"storj.io/common/testrand"
+	"storj.io/uplink/private/eestream"
 )
 
 func TestUnique(t *testing.T) {
@@ -44,3 +47,59 @@ func TestUnique(t *testing.T) {
 		assert.Equal(t, tt.unique, unique(tt.limits), errTag)
 	}
 }
+
+func TestFailedNodesExclude(t *testing.T) {
+	limits := make([]*pb.AddressedOrderLimit, 4)
+	for i := 0; i < len(limits); i++ {
+		limits[i] = &pb.AddressedOrderLimit{
+			Limit: &pb.OrderLimit{
+				StorageNodeId: testrand.NodeID(),
+			},
+		}
+	}
+	limits[3] = nil
+
+	var nilFailed *FailedNodes
+	assert.Equal(t, limits, nilFailed.exclude(limits, 2))
+
+	failed := NewFailedNodes()
+	assert.Equal(t, limits, failed.exclude(limits, 2))
+
+	failed.Add(limits[1].GetLimit().StorageNodeId)
+	assert.True(t, failed.Contains(limits[1].GetLimit().StorageNodeId))
+	assert.False(t, failed.Contains(limits[0].GetLimit().StorageNodeId))
+	assert.Equal(t, []*pb.AddressedOrderLimit{limits[0], nil, limits[2], nil}, failed.exclude(limits, 2))
+
+	// the failed nodes are used, when there wouldn't be enough limits left
+	failed.Add(limits[2].GetLimit().StorageNodeId)
+	assert.Equal(t, 2, failed.Count())
+	assert.Equal(t, limits, failed.exclude(limits, 2))
+}
+
+func TestCheckUploadResults(t *testing.T) {
+	rs, err := eestream.NewRedundancyStrategyFromStorj(storj.RedundancyScheme{
+		Algorithm:      storj.ReedSolomon,
+		ShareSize:      256,
+		RequiredShares: 2,
+		RepairShares:   3,
+		OptimalShares:  5,
+		TotalShares:    6,
+	})
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		uploaded int
+		timedOut bool
+		ok       bool
+	}{
+		{uploaded: 3, timedOut: false, ok: false},
+		{uploaded: 4, timedOut: false, ok: false},
+		{uploaded: 5, timedOut: false, ok: true},
+		{uploaded: 3, timedOut: true, ok: false},
+		{uploaded: 4, timedOut: true, ok: true},
+		{uploaded: 6, timedOut: true, ok: true},
+	} {
+		err := checkUploadResults(tt.uploaded, rs, tt.timedOut)
+		assert.Equal(t, tt.ok, err == nil, "uploaded %d, timed out %v", tt.uploaded, tt.timedOut)
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package ecclient
+
+import (
+	"context"
+	"sync"
+
+	"storj.io/common/pb"
+	"storj.io/common/storj"
+)
+
+// FailedNodes collects the storage nodes, whose piece downloads failed. The
+// segment downloads started with a context containing it skip these nodes,
+// as long as enough other nodes are left.
+type FailedNodes struct {
+	mu    sync.Mutex
+	nodes map[storj.NodeID]struct{}
+}
+
+// NewFailedNodes returns an empty set of failed nodes.
+func NewFailedNodes() *FailedNodes {
+	return &FailedNodes{nodes: map[storj.NodeID]struct{}{}}
+}
+
+// Add adds the node to the failed nodes.
+func (failed *FailedNodes) Add(id storj.NodeID) {
+	if failed == nil {
+		return
+	}
+
+	failed.mu.Lock()
+	defer failed.mu.Unlock()
+	failed.nodes[id] = struct{}{}
+}
+
+// Contains returns whether the node has failed.
+func (failed *FailedNodes) Contains(id storj.NodeID) bool {
+	if failed == nil {
+		return false
+	}
+
+	failed.mu.Lock()
+	defer failed.mu.Unlock()
+	_, ok := failed.nodes[id]
+	return ok
+}
+
+// Count returns the number of the failed nodes.
+func (failed *FailedNodes) Count() int {
+	if failed == nil {
+		return 0
+	}
+
+	failed.mu.Lock()
+	defer failed.mu.Unlock()
+	return len(failed.nodes)
+}
+
+// exclude returns the limits without the ones of the failed nodes, unless
+// fewer than required limits would be left.
+func (failed *FailedNodes) exclude(limits []*pb.AddressedOrderLimit, required int) []*pb.AddressedOrderLimit {
+	if failed.Count() == 0 {
+		return limits
+	}
+
+	excluded := make([]*pb.AddressedOrderLimit, len(limits))
+	for i, limit := range limits {
+		if limit != nil && !failed.Contains(limit.GetLimit().StorageNodeId) {
+			excluded[i] = limit
+		}
+	}
+	if nonNilCount(excluded) < required {
+		return limits
+	}
+	return excluded
+}
+
+// The key type is unexported to prevent collisions with context keys defined in
+// other packages.
+type failedNodesKey struct{}
+
+// WithFailedNodes returns a context, whose segment downloads record the nodes,
+// whose piece downloads fail, in failed and skip the nodes already in it.
+func WithFailedNodes(ctx context.Context, failed *FailedNodes) context.Context {
+	return context.WithValue(ctx, failedNodesKey{}, failed)
+}
+
+// FailedNodesFromContext returns the failed nodes set with WithFailedNodes.
+func FailedNodesFromContext(ctx context.Context) *FailedNodes {
+	failed, _ := ctx.Value(failedNodesKey{}).(*FailedNodes)
+	return failed
+}
@@ -9,3 +9,7 @@ import (
 
 // Error is the default eestream errs class.
 var Error = errs.Class("eestream")
+
+// ErrNotEnoughPieces is the error class of a stripe, for which not enough
+// erasure shares could be downloaded from the storage nodes.
+var ErrNotEnoughPieces = errs.Class("not enough pieces")
@@ -40,7 +40,16 @@ type decodedReader struct {
 // set to 0, the minimum possible memory will be used.
 // if forceErrorDetection is set to true then k+1 pieces will be always
 // required for decoding, so corrupted pieces can be detected.
+// The read buffers spill to temporary files when it's configured with WithSpill
+// and the erasure shares are verified when it's configured with
+// WithVerification.
 func DecodeReaders2(ctx context.Context, cancel func(), rs map[int]io.ReadCloser, es ErasureScheme, expectedSize int64, mbm int, forceErrorDetection bool) io.ReadCloser {
+	return decodeReaders(ctx, cancel, rs, es, expectedSize, mbm, forceErrorDetection, nil)
+}
+
+// decodeReaders is DecodeReaders2, where the readers are started and
+// canceled by scheduler.
+func decodeReaders(ctx context.Context, cancel func(), rs map[int]io.ReadCloser, es ErasureScheme, expectedSize int64, mbm int, forceErrorDetection bool, scheduler *pieceScheduler) io.ReadCloser {
 	defer mon.Task()(&ctx)(nil)
 	if expectedSize < 0 {
 		return readcloser.FatalReadCloser(Error.New("negative expected size"))
@@ -53,13 +62,19 @@ func DecodeReaders2(ctx context.Context, cancel func(), rs map[int]io.ReadCloser
 	if err := checkMBM(mbm); err != nil {
 		return readcloser.FatalReadCloser(err)
 	}
+	spill, _ := SpillFromContext(ctx)
+	corrected, verify := verificationFromContext(ctx)
+	if verify {
+		forceErrorDetection = true
+	}
 	dr := &decodedReader{
 		readers:         rs,
 		scheme:          es,
-		stripeReader:    NewStripeReader(rs, es, mbm, forceErrorDetection),
+		stripeReader:    newStripeReader(rs, es, mbm, forceErrorDetection, spill, scheduler),
 		outbuf:          make([]byte, 0, es.StripeSize()),
 		expectedStripes: expectedSize / int64(es.StripeSize()),
 	}
+	dr.stripeReader.corrected = corrected
 	dr.ctx, dr.cancel = ctx, cancel
 	// Kick off a goroutine to watch for context cancelation.
 	go func() {
@@ -149,6 +164,8 @@ type decodedRanger struct {
 // set to 0, the minimum possible memory will be used.
 // if forceErrorDetection is set to true then k+1 pieces will be always
 // required for decoding, so corrupted pieces can be detected.
+// The ranges of the pieces are read as configured with WithLongTail and
+// verified as configured with WithVerification.
 func Decode(rrs map[int]ranger.Ranger, es ErasureScheme, mbm int, forceErrorDetection bool) (ranger.Ranger, error) {
 	if err := checkMBM(mbm); err != nil {
 		return nil, err
@@ -194,19 +211,33 @@ func (dr *decodedRanger) Range(ctx context.Context, offset, length int64) (_ io.
 	// blocks contain this request
 	firstBlock, blockCount := encryption.CalcEncompassingBlocks(offset, length, dr.es.StripeSize())
 
+	// the pieces are downloaded as configured with WithLongTail.
+	var scheduler *pieceScheduler
+	if config, ok := LongTailFromContext(ctx); ok {
+		pieces := make([]int, 0, len(dr.rrs))
+		for i := range dr.rrs {
+			pieces = append(pieces, i)
+		}
+		required := dr.es.RequiredCount()
+		if _, verify := verificationFromContext(ctx); dr.forceErrorDetection || verify {
+			required++
+		}
+		scheduler = newPieceScheduler(config, pieces, required)
+	}
+
 	// go ask for ranges for all those block boundaries
 	readers := make(map[int]io.ReadCloser, len(dr.rrs))
 	for i, rr := range dr.rrs {
-		r, err := rr.Range(ctx, firstBlock*int64(dr.es.ErasureShareSize()), blockCount*int64(dr.es.ErasureShareSize()))
+		pieceCtx := scheduler.pieceContext(ctx, i)
+		r, err := rr.Range(pieceCtx, firstBlock*int64(dr.es.ErasureShareSize()), blockCount*int64(dr.es.ErasureShareSize()))
 		if err != nil {
-			readers[i] = readcloser.FatalReadCloser(err)
-		} else {
-			readers[i] = r
+			r = readcloser.FatalReadCloser(err)
 		}
+		readers[i] = scheduler.reader(pieceCtx, i, r)
 	}
 
 	// decode from all those ranges
-	r := DecodeReaders2(ctx, cancel, readers, dr.es, blockCount*int64(dr.es.StripeSize()), dr.mbm, dr.forceErrorDetection)
+	r := decodeReaders(ctx, cancel, readers, dr.es, blockCount*int64(dr.es.StripeSize()), dr.mbm, dr.forceErrorDetection, scheduler)
 	// offset might start a few bytes in, potentially discard the initial bytes
 	_, err = io.CopyN(ioutil.Discard, r, offset-firstBlock*int64(dr.es.StripeSize()))
 	if err != nil {
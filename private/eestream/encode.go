@@ -136,7 +136,8 @@ type encodedReader struct {
 }
 
 // EncodeReader2 takes a Reader and a RedundancyStrategy and returns a slice of
-// io.ReadClosers.
+// io.ReadClosers. The data is buffered as configured with WithSpill, or
+// otherwise with fpath.WithTempData.
 func EncodeReader2(ctx context.Context, r io.Reader, rs RedundancyStrategy) (_ []io.ReadCloser, err error) {
 	defer mon.Task()(&ctx)(&err)
 
@@ -150,7 +151,9 @@ func EncodeReader2(ctx context.Context, r io.Reader, rs RedundancyStrategy) (_ [
 	var pipeWriter sync2.PipeWriter
 
 	tempDir, inmemory, _ := fpath.GetTempData(ctx)
-	if inmemory {
+	if spill, ok := SpillFromContext(ctx); ok && spill.Enabled() {
+		pipeReaders, pipeWriter, err = newSpillTee(rs.TotalCount(), spill)
+	} else if inmemory {
 		// TODO what default inmemory size will be enough
 		pipeReaders, pipeWriter, err = sync2.NewTeeInmemory(rs.TotalCount(), memory.MiB.Int64())
 	} else {
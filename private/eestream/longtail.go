@@ -0,0 +1,277 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package eestream
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// LongTail configures how many pieces of a segment are downloaded at once and
+// how the slow piece downloads are handled.
+type LongTail struct {
+	// ExtraPieces is the number of piece downloads started in addition to the
+	// pieces required for decoding. The other pieces are spares, which are
+	// started when a started piece download fails or is slower than
+	// HedgeAfter. Negative value starts all pieces at once.
+	ExtraPieces int
+	// HedgeAfter starts a spare piece download, when a read from a started
+	// piece download takes longer, and another one after each further
+	// HedgeAfter the read takes. Zero disables hedging.
+	HedgeAfter time.Duration
+	// CancelSlow cancels the piece downloads, which haven't delivered their
+	// erasure share of a stripe by the time the stripe is decoded.
+	CancelSlow bool
+}
+
+// The key type is unexported to prevent collisions with context keys defined in
+// other packages.
+type longTailKey struct{}
+
+// WithLongTail returns a context, which configures the piece downloads of
+// the decoding started with it.
+func WithLongTail(ctx context.Context, config LongTail) context.Context {
+	return context.WithValue(ctx, longTailKey{}, config)
+}
+
+// LongTailFromContext returns the long tail configuration set with
+// WithLongTail.
+func LongTailFromContext(ctx context.Context) (LongTail, bool) {
+	config, ok := ctx.Value(longTailKey{}).(LongTail)
+	return config, ok
+}
+
+// UploadLongTail configures when the piece uploads of a segment are finished.
+type UploadLongTail struct {
+	// Timeout cancels the remaining piece uploads, once it passes after more
+	// pieces than the repair threshold have been uploaded. The segment upload
+	// succeeds then also below the optimal threshold. Zero disables it.
+	Timeout time.Duration
+	// WaitForAll waits for all piece uploads, instead of canceling the
+	// remaining ones, once the optimal threshold has been reached.
+	WaitForAll bool
+}
+
+// The key type is unexported to prevent collisions with context keys defined in
+// other packages.
+type uploadLongTailKey struct{}
+
+// WithUploadLongTail returns a context, which configures the segment uploads
+// started with it.
+func WithUploadLongTail(ctx context.Context, config UploadLongTail) context.Context {
+	return context.WithValue(ctx, uploadLongTailKey{}, config)
+}
+
+// UploadLongTailFromContext returns the upload long tail configuration set
+// with WithUploadLongTail.
+func UploadLongTailFromContext(ctx context.Context) (UploadLongTail, bool) {
+	config, ok := ctx.Value(uploadLongTailKey{}).(UploadLongTail)
+	return config, ok
+}
+
+// pieceScheduler starts the spare piece downloads of a decoding and cancels
+// the slow ones. A nil scheduler starts all piece downloads at once.
+type pieceScheduler struct {
+	config LongTail
+
+	mu       sync.Mutex
+	spares   []int // pieces, which haven't been started yet
+	start    map[int]chan struct{}
+	cancel   map[int]func()
+	canceled map[int]bool
+}
+
+// newPieceScheduler creates a scheduler, which starts required plus the
+// configured extra number of the pieces in random order.
+func newPieceScheduler(config LongTail, pieces []int, required int) *pieceScheduler {
+	pieces = append([]int(nil), pieces...)
+	rand.Shuffle(len(pieces), func(i, k int) {
+		pieces[i], pieces[k] = pieces[k], pieces[i]
+	})
+
+	started := len(pieces)
+	if config.ExtraPieces >= 0 && required+config.ExtraPieces < started {
+		started = required + config.ExtraPieces
+	}
+
+	scheduler := &pieceScheduler{
+		config:   config,
+		spares:   pieces[started:],
+		start:    make(map[int]chan struct{}, len(pieces)),
+		cancel:   make(map[int]func(), len(pieces)),
+		canceled: make(map[int]bool),
+	}
+	for _, num := range scheduler.spares {
+		scheduler.start[num] = make(chan struct{})
+	}
+	return scheduler
+}
+
+// pieceContext returns the context for the download of the piece num, which
+// is canceled when the download is slow.
+func (scheduler *pieceScheduler) pieceContext(ctx context.Context, num int) context.Context {
+	if scheduler == nil {
+		return ctx
+	}
+	ctx, cancel := context.WithCancel(ctx)
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	scheduler.cancel[num] = cancel
+	return ctx
+}
+
+// reader returns a reader of the piece num, which waits until the piece is
+// started and starts a spare piece when it's slow.
+func (scheduler *pieceScheduler) reader(ctx context.Context, num int, reader io.ReadCloser) io.ReadCloser {
+	if scheduler == nil {
+		return reader
+	}
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	return &scheduledReader{
+		ReadCloser: reader,
+		ctx:        ctx,
+		scheduler:  scheduler,
+		num:        num,
+		start:      scheduler.start[num],
+	}
+}
+
+// waiting returns the number of pieces, which haven't been started yet.
+func (scheduler *pieceScheduler) waiting() int {
+	if scheduler == nil {
+		return 0
+	}
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	return len(scheduler.spares)
+}
+
+// startSpare starts the next spare piece. It returns false when there are no
+// spares left.
+func (scheduler *pieceScheduler) startSpare() bool {
+	if scheduler == nil {
+		return false
+	}
+
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if len(scheduler.spares) == 0 {
+		return false
+	}
+
+	num := scheduler.spares[0]
+	scheduler.spares = scheduler.spares[1:]
+	close(scheduler.start[num])
+	return true
+}
+
+// failed starts a spare instead of the failed piece num, unless it failed,
+// because it has been canceled.
+func (scheduler *pieceScheduler) failed(num int) {
+	if scheduler == nil || scheduler.isCanceled(num) {
+		return
+	}
+	scheduler.startSpare()
+}
+
+// hedge starts a spare next to the slow piece num.
+func (scheduler *pieceScheduler) hedge(num int) {
+	if scheduler.isCanceled(num) {
+		return
+	}
+	scheduler.startSpare()
+}
+
+// cancelsSlow returns whether the slow pieces should be canceled.
+func (scheduler *pieceScheduler) cancelsSlow() bool {
+	return scheduler != nil && scheduler.config.CancelSlow
+}
+
+// cancelPiece cancels the download of the piece num, if it has been started.
+func (scheduler *pieceScheduler) cancelPiece(num int) {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+
+	if scheduler.canceled[num] {
+		return
+	}
+	if start, ok := scheduler.start[num]; ok {
+		select {
+		case <-start:
+		default:
+			// spares, which haven't been started, aren't slow.
+			return
+		}
+	}
+
+	scheduler.canceled[num] = true
+	if cancel, ok := scheduler.cancel[num]; ok {
+		cancel()
+	}
+}
+
+func (scheduler *pieceScheduler) isCanceled(num int) bool {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	return scheduler.canceled[num]
+}
+
+// scheduledReader is a reader of a piece, which waits for start to be closed
+// before reading. It's read from a single goroutine.
+type scheduledReader struct {
+	io.ReadCloser
+	ctx       context.Context
+	scheduler *pieceScheduler
+	num       int
+	start     chan struct{}
+}
+
+// Read implements io.Reader.
+func (reader *scheduledReader) Read(p []byte) (n int, err error) {
+	if reader.start != nil {
+		select {
+		case <-reader.start:
+			reader.start = nil
+		case <-reader.ctx.Done():
+			return 0, reader.ctx.Err()
+		}
+	}
+
+	hedgeAfter := reader.scheduler.config.HedgeAfter
+	if hedgeAfter <= 0 {
+		return reader.ReadCloser.Read(p)
+	}
+
+	var mu sync.Mutex
+	var stopped bool
+	var timer *time.Timer
+
+	mu.Lock()
+	timer = time.AfterFunc(hedgeAfter, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if stopped {
+			return
+		}
+		reader.scheduler.hedge(reader.num)
+		timer.Reset(hedgeAfter)
+	})
+	mu.Unlock()
+
+	n, err = reader.ReadCloser.Read(p)
+
+	mu.Lock()
+	stopped = true
+	timer.Stop()
+	mu.Unlock()
+
+	return n, err
+}
@@ -0,0 +1,184 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package eestream_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vivint/infectious"
+
+	"storj.io/common/memory"
+	"storj.io/common/ranger"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/uplink/private/eestream"
+)
+
+// pieceBehavior is how a testPieceRanger behaves, once it's read from.
+type pieceBehavior int
+
+const (
+	pieceGood pieceBehavior = iota
+	pieceFailing
+	pieceStalled
+)
+
+// testPieceRanger records whether its readers are read from.
+type testPieceRanger struct {
+	ranger.Ranger
+	behavior pieceBehavior
+
+	mu      sync.Mutex
+	ctx     context.Context
+	started bool
+}
+
+func (rr *testPieceRanger) Range(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	reader, err := rr.Ranger.Range(ctx, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	rr.mu.Lock()
+	rr.ctx = ctx
+	rr.mu.Unlock()
+	return &testPieceReader{ReadCloser: reader, ranger: rr, ctx: ctx}, nil
+}
+
+func (rr *testPieceRanger) isStarted() bool {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	return rr.started
+}
+
+func (rr *testPieceRanger) isCanceled() bool {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	return rr.ctx.Err() != nil
+}
+
+type testPieceReader struct {
+	io.ReadCloser
+	ranger *testPieceRanger
+	ctx    context.Context
+}
+
+func (reader *testPieceReader) Read(p []byte) (int, error) {
+	reader.ranger.mu.Lock()
+	reader.ranger.started = true
+	reader.ranger.mu.Unlock()
+
+	switch reader.ranger.behavior {
+	case pieceFailing:
+		return 0, errors.New("piece failed")
+	case pieceStalled:
+		<-reader.ctx.Done()
+		return 0, reader.ctx.Err()
+	}
+	return reader.ReadCloser.Read(p)
+}
+
+func TestLongTail(t *testing.T) {
+	ctx := testcontext.New(t)
+
+	fc, err := infectious.NewFEC(2, 5)
+	require.NoError(t, err)
+	es := eestream.NewRSScheme(fc, 1024)
+	rs, err := eestream.NewRedundancyStrategy(es, 0, 0)
+	require.NoError(t, err)
+
+	data := testrand.Bytes(64 * memory.KiB)
+	readers, err := eestream.EncodeReader2(ctx, bytes.NewReader(data), rs)
+	require.NoError(t, err)
+	pieces, err := readAll(readers)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name      string
+		config    eestream.LongTail
+		behaviors []pieceBehavior
+		started   int
+		canceled  bool // whether the stalled pieces are canceled
+	}{
+		{
+			name:      "all pieces",
+			config:    eestream.LongTail{ExtraPieces: -1},
+			behaviors: []pieceBehavior{pieceGood, pieceGood, pieceGood, pieceGood, pieceGood},
+			started:   5,
+		},
+		{
+			name:      "required pieces",
+			config:    eestream.LongTail{},
+			behaviors: []pieceBehavior{pieceGood, pieceGood, pieceGood, pieceGood, pieceGood},
+			started:   2,
+		},
+		{
+			name:      "extra piece",
+			config:    eestream.LongTail{ExtraPieces: 1},
+			behaviors: []pieceBehavior{pieceGood, pieceGood, pieceGood, pieceGood, pieceGood},
+			started:   3,
+		},
+		{
+			name:      "spares replace failed pieces",
+			config:    eestream.LongTail{},
+			behaviors: []pieceBehavior{pieceFailing, pieceFailing, pieceFailing, pieceGood, pieceGood},
+			started:   -1,
+		},
+		{
+			name:      "hedging stalled pieces",
+			config:    eestream.LongTail{HedgeAfter: 10 * time.Millisecond},
+			behaviors: []pieceBehavior{pieceStalled, pieceStalled, pieceGood, pieceGood, pieceGood},
+			started:   -1,
+		},
+		{
+			name:      "canceling stalled pieces",
+			config:    eestream.LongTail{ExtraPieces: -1, CancelSlow: true},
+			behaviors: []pieceBehavior{pieceStalled, pieceGood, pieceGood, pieceGood, pieceStalled},
+			started:   5,
+			canceled:  true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			rangers := make([]*testPieceRanger, len(pieces))
+			rrs := make(map[int]ranger.Ranger, len(pieces))
+			for i, piece := range pieces {
+				rangers[i] = &testPieceRanger{Ranger: ranger.ByteRanger(piece), behavior: tc.behaviors[i]}
+				rrs[i] = rangers[i]
+			}
+
+			rr, err := eestream.Decode(rrs, rs, 0, false)
+			require.NoError(t, err)
+
+			reader, err := rr.Range(eestream.WithLongTail(ctx, tc.config), 0, rr.Size())
+			require.NoError(t, err)
+			decoded, err := ioutil.ReadAll(reader)
+			require.NoError(t, err)
+			for i, rr := range rangers {
+				if tc.behaviors[i] == pieceStalled {
+					require.Equal(t, tc.canceled, rr.isCanceled())
+				}
+			}
+			require.NoError(t, reader.Close())
+			require.Equal(t, data, decoded)
+
+			if tc.started >= 0 {
+				started := 0
+				for _, rr := range rangers {
+					if rr.isStarted() {
+						started++
+					}
+				}
+				require.Equal(t, tc.started, started)
+			}
+		})
+	}
+}
@@ -10,7 +10,8 @@ import (
 
 // PieceBuffer is a synchronized buffer for storing erasure shares for a piece.
 type PieceBuffer struct {
-	buf          []byte
+	storage      pieceStorage
+	size         int
 	shareSize    int
 	cond         *sync.Cond
 	newDataCond  *sync.Cond
@@ -26,8 +27,15 @@ type PieceBuffer struct {
 // internal content. If new data is written to the buffer, newDataCond will be
 // notified.
 func NewPieceBuffer(buf []byte, shareSize int, newDataCond *sync.Cond) *PieceBuffer {
+	return newPieceBuffer(memoryStorage(buf), len(buf), shareSize, newDataCond)
+}
+
+// newPieceBuffer creates and initializes a new PieceBuffer, which stores
+// size bytes in storage.
+func newPieceBuffer(storage pieceStorage, size int, shareSize int, newDataCond *sync.Cond) *PieceBuffer {
 	return &PieceBuffer{
-		buf:         buf,
+		storage:     storage,
+		size:        size,
 		shareSize:   shareSize,
 		cond:        sync.NewCond(&sync.Mutex{}),
 		newDataCond: newDataCond,
@@ -51,16 +59,22 @@ func (b *PieceBuffer) Read(p []byte) (n int, err error) {
 	}
 
 	if b.rpos >= b.wpos {
-		nn := copy(p, b.buf[b.rpos:])
+		nn, err := b.readAt(p, b.size)
 		n += nn
-		b.rpos = (b.rpos + nn) % len(b.buf)
+		b.rpos = (b.rpos + nn) % b.size
 		p = p[nn:]
+		if err != nil {
+			return n, err
+		}
 	}
 
 	if b.rpos < b.wpos {
-		nn := copy(p, b.buf[b.rpos:b.wpos])
+		nn, err := b.readAt(p, b.wpos)
 		n += nn
 		b.rpos += nn
+		if err != nil {
+			return n, err
+		}
 	}
 
 	if n > 0 {
@@ -87,11 +101,11 @@ func (b *PieceBuffer) Skip(n int) error {
 		}
 
 		if b.rpos >= b.wpos {
-			if len(b.buf)-b.rpos > n {
-				b.rpos = (b.rpos + n) % len(b.buf)
+			if b.size-b.rpos > n {
+				b.rpos = (b.rpos + n) % b.size
 				n = 0
 			} else {
-				n -= len(b.buf) - b.rpos
+				n -= b.size - b.rpos
 				b.rpos = 0
 			}
 		} else {
@@ -144,27 +158,41 @@ func (b *PieceBuffer) write(p []byte) (n int, err error) {
 		b.cond.Wait()
 	}
 
-	var wr int
+	limit := b.size
 	if b.wpos < b.rpos {
-		wr = copy(b.buf[b.wpos:b.rpos], p)
-	} else {
-		wr = copy(b.buf[b.wpos:], p)
+		limit = b.rpos
+	}
+	if len(p) > limit-b.wpos {
+		p = p[:limit-b.wpos]
 	}
 
+	wr, err := b.storage.WriteAt(p, int64(b.wpos))
 	n += wr
-	b.wpos = (b.wpos + wr) % len(b.buf)
-	if b.wpos == b.rpos {
+	b.wpos = (b.wpos + wr) % b.size
+	if wr > 0 && b.wpos == b.rpos {
 		b.full = true
 	}
 
-	return n, nil
+	return n, err
+}
+
+// readAt reads into p from the read position up to limit. The caller must
+// hold the lock.
+func (b *PieceBuffer) readAt(p []byte, limit int) (int, error) {
+	if len(p) > limit-b.rpos {
+		p = p[:limit-b.rpos]
+	}
+	return b.storage.ReadAt(p, int64(b.rpos))
 }
 
 // Close sets io.ErrClosedPipe to the buffer to prevent further writes and
-// blocking on read.
+// blocking on read. It releases the storage of the buffer.
 func (b *PieceBuffer) Close() error {
 	b.SetError(io.ErrClosedPipe)
-	return nil
+
+	b.cond.L.Lock()
+	defer b.cond.L.Unlock()
+	return b.storage.Close()
 }
 
 // SetError sets an error to be returned by Read and Write. Read will return
@@ -214,9 +242,9 @@ func (b *PieceBuffer) buffered() int {
 	case b.rpos < b.wpos:
 		return b.wpos - b.rpos
 	case b.rpos > b.wpos:
-		return len(b.buf) + b.wpos - b.rpos
+		return b.size + b.wpos - b.rpos
 	case b.full:
-		return len(b.buf)
+		return b.size
 	default: // empty
 		return 0
 	}
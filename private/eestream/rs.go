@@ -4,6 +4,9 @@
 package eestream
 
 import (
+	"bytes"
+	"sort"
+
 	"github.com/vivint/infectious"
 )
 
@@ -36,6 +39,42 @@ func (s *rsScheme) Decode(out []byte, in map[int][]byte) ([]byte, error) {
 	return s.fc.Decode(out, shares)
 }
 
+// DecodeCorrected decodes like Decode and returns also the numbers of the
+// erasure shares, which were corrected. The data of in isn't modified.
+func (s *rsScheme) DecodeCorrected(out []byte, in map[int][]byte) (_ []byte, corrected []int, err error) {
+	shares := make([]infectious.Share, 0, len(in))
+	for num, data := range in {
+		shares = append(shares, infectious.Share{Number: num, Data: append([]byte(nil), data...)})
+	}
+
+	err = s.fc.Correct(shares)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, share := range shares {
+		if !bytes.Equal(share.Data, in[share.Number]) {
+			corrected = append(corrected, share.Number)
+		}
+	}
+	sort.Ints(corrected)
+
+	// the shares are corrected already, so they are only rebuilt, instead of
+	// correcting them again in Decode.
+	size := len(shares[0].Data) * s.fc.Required()
+	if cap(out) < size {
+		out = make([]byte, size)
+	} else {
+		out = out[:size]
+	}
+	err = s.fc.Rebuild(shares, func(share infectious.Share) {
+		copy(out[share.Number*len(share.Data):], share.Data)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, corrected, nil
+}
+
 func (s *rsScheme) ErasureShareSize() int {
 	return s.erasureShareSize
 }
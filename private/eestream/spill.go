@@ -0,0 +1,342 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package eestream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/calebcase/tmpfile"
+
+	"storj.io/common/memory"
+	"storj.io/common/sync2"
+)
+
+// SpillConfig configures the buffers of the erasure encoding and decoding to
+// store their data in temporary files after an in-memory threshold is exceeded.
+type SpillConfig struct {
+	// Directory is where the temporary files are created. It defaults to
+	// os.TempDir().
+	Directory string
+	// MemoryThreshold is the number of bytes each buffer keeps in memory.
+	// Spilling is disabled when it's zero.
+	MemoryThreshold int64
+}
+
+// Enabled returns whether the buffers spill to temporary files.
+func (config SpillConfig) Enabled() bool {
+	return config.MemoryThreshold > 0
+}
+
+func (config SpillConfig) directory() string {
+	if config.Directory == "" {
+		return os.TempDir()
+	}
+	return config.Directory
+}
+
+// The key type is unexported to prevent collisions with context keys defined in
+// other packages.
+type spillKey struct{}
+
+// WithSpill returns a context, which configures the buffers of the erasure
+// encoding and decoding started with it.
+func WithSpill(ctx context.Context, config SpillConfig) context.Context {
+	return context.WithValue(ctx, spillKey{}, config)
+}
+
+// SpillFromContext returns the spill configuration set with WithSpill.
+func SpillFromContext(ctx context.Context) (SpillConfig, bool) {
+	config, ok := ctx.Value(spillKey{}).(SpillConfig)
+	return config, ok
+}
+
+// newSpillTee returns a tee that keeps the first threshold bytes in memory
+// and stores the rest in a temporary file, which is created only when it's
+// needed.
+func newSpillTee(readers int, config SpillConfig) ([]sync2.PipeReader, sync2.PipeWriter, error) {
+	blockSize := memory.MiB.Int64()
+	if config.MemoryThreshold < blockSize {
+		blockSize = config.MemoryThreshold
+	}
+
+	memoryReaders, memoryWriter, err := sync2.NewTeeInmemory(readers, blockSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tee := &spillTee{
+		config:  config,
+		spilled: make(chan struct{}),
+		closed:  make([]bool, readers),
+	}
+
+	teeReaders := make([]sync2.PipeReader, readers)
+	for i := range teeReaders {
+		teeReaders[i] = &spillTeeReader{
+			tee:     tee,
+			num:     i,
+			current: memoryReaders[i],
+		}
+	}
+
+	return teeReaders, &spillTeeWriter{
+		tee:     tee,
+		current: memoryWriter,
+		memory:  config.MemoryThreshold,
+	}, nil
+}
+
+// spillTee tracks the file tee, which the readers continue with after they
+// have read the data from memory.
+type spillTee struct {
+	config SpillConfig
+
+	// spilled is closed when the writer has finished writing to memory.
+	spilled chan struct{}
+
+	mu          sync.Mutex
+	fileReaders []sync2.PipeReader
+	closed      []bool
+}
+
+// spill creates the file tee. The readers, which are already closed, are
+// closed in it immediately, so that the temporary file is closed when all
+// the others are done.
+func (tee *spillTee) spill() (sync2.PipeWriter, error) {
+	fileReaders, fileWriter, err := sync2.NewTeeFile(len(tee.closed), tee.config.directory())
+	if err != nil {
+		return nil, err
+	}
+
+	tee.mu.Lock()
+	defer tee.mu.Unlock()
+
+	tee.fileReaders = fileReaders
+	for i, closed := range tee.closed {
+		if closed {
+			_ = fileReaders[i].Close()
+		}
+	}
+	return fileWriter, nil
+}
+
+// fileReader returns the num-th reader of the file tee or nil, when the
+// writer finished without spilling.
+func (tee *spillTee) fileReader(num int) sync2.PipeReader {
+	<-tee.spilled
+
+	tee.mu.Lock()
+	defer tee.mu.Unlock()
+
+	if tee.fileReaders == nil {
+		return nil
+	}
+	return tee.fileReaders[num]
+}
+
+// close marks the num-th reader as closed and returns its reader of the file
+// tee, if there is one.
+func (tee *spillTee) close(num int) sync2.PipeReader {
+	tee.mu.Lock()
+	defer tee.mu.Unlock()
+
+	tee.closed[num] = true
+	if tee.fileReaders == nil {
+		return nil
+	}
+	return tee.fileReaders[num]
+}
+
+type spillTeeReader struct {
+	tee     *spillTee
+	num     int
+	current sync2.PipeReader
+	inFile  bool
+}
+
+type spillTeeWriter struct {
+	tee     *spillTee
+	current sync2.PipeWriter
+	memory  int64 // bytes that can still be written to memory
+	inFile  bool
+	done    bool
+}
+
+// Read implements io.Reader.
+func (reader *spillTeeReader) Read(data []byte) (n int, err error) {
+	n, err = reader.current.Read(data)
+	if !errors.Is(err, io.EOF) || reader.inFile {
+		return n, err
+	}
+
+	reader.inFile = true
+	fileReader := reader.tee.fileReader(reader.num)
+	if fileReader == nil {
+		return n, err
+	}
+
+	// the data in memory is finished, but it still needs to be closed.
+	_ = reader.current.Close()
+	reader.current = fileReader
+
+	if n > 0 {
+		return n, nil
+	}
+	return reader.current.Read(data)
+}
+
+// Close implements io.Closer.
+func (reader *spillTeeReader) Close() error { return reader.CloseWithError(nil) }
+
+// CloseWithError implements closing with error.
+func (reader *spillTeeReader) CloseWithError(reason error) error {
+	fileReader := reader.tee.close(reader.num)
+	err := reader.current.CloseWithError(reason)
+	if fileReader != nil && fileReader != reader.current {
+		_ = fileReader.CloseWithError(reason)
+	}
+	return err
+}
+
+// Write implements io.Writer.
+func (writer *spillTeeWriter) Write(data []byte) (n int, err error) {
+	if !writer.inFile && int64(len(data)) > writer.memory {
+		if writer.memory > 0 {
+			n, err = writer.current.Write(data[:writer.memory])
+			writer.memory -= int64(n)
+			if err != nil {
+				return n, err
+			}
+		}
+
+		fileWriter, err := writer.tee.spill()
+		if err != nil {
+			return n, err
+		}
+
+		// the file tee has to be in place before the readers reach the end
+		// of the data in memory.
+		memoryWriter := writer.current
+		writer.current, writer.inFile = fileWriter, true
+		close(writer.tee.spilled)
+		if err := memoryWriter.Close(); err != nil {
+			return n, err
+		}
+
+		data = data[n:]
+	}
+
+	nn, err := writer.current.Write(data)
+	if !writer.inFile {
+		writer.memory -= int64(nn)
+	}
+	return n + nn, err
+}
+
+// Close implements io.Closer.
+func (writer *spillTeeWriter) Close() error { return writer.CloseWithError(nil) }
+
+// CloseWithError implements closing with error.
+func (writer *spillTeeWriter) CloseWithError(reason error) error {
+	if writer.done {
+		return io.ErrClosedPipe
+	}
+	writer.done = true
+
+	if !writer.inFile {
+		close(writer.tee.spilled)
+	}
+	return writer.current.CloseWithError(reason)
+}
+
+// pieceStorage stores the data of a PieceBuffer.
+type pieceStorage interface {
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+}
+
+// memoryStorage is a pieceStorage in memory.
+type memoryStorage []byte
+
+func (storage memoryStorage) ReadAt(p []byte, off int64) (int, error) {
+	return copy(p, storage[off:]), nil
+}
+
+func (storage memoryStorage) WriteAt(p []byte, off int64) (int, error) {
+	return copy(storage[off:], p), nil
+}
+
+func (storage memoryStorage) Close() error { return nil }
+
+// spillStorage is a pieceStorage, which keeps the beginning of the data in
+// memory and the rest in a temporary file.
+type spillStorage struct {
+	directory string
+	memory    []byte
+	file      *os.File
+	closed    bool
+}
+
+func newSpillStorage(config SpillConfig) *spillStorage {
+	return &spillStorage{
+		directory: config.directory(),
+		memory:    make([]byte, config.MemoryThreshold),
+	}
+}
+
+func (storage *spillStorage) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < int64(len(storage.memory)) {
+		n = copy(p, storage.memory[off:])
+		p, off = p[n:], off+int64(n)
+	}
+	if len(p) == 0 {
+		return n, nil
+	}
+	if storage.file == nil {
+		return n, io.ErrClosedPipe
+	}
+
+	nn, err := storage.file.ReadAt(p, off-int64(len(storage.memory)))
+	if errors.Is(err, io.EOF) && nn == len(p) {
+		err = nil
+	}
+	return n + nn, err
+}
+
+func (storage *spillStorage) WriteAt(p []byte, off int64) (n int, err error) {
+	if off < int64(len(storage.memory)) {
+		n = copy(storage.memory[off:], p)
+		p, off = p[n:], off+int64(n)
+	}
+	if len(p) == 0 {
+		return n, nil
+	}
+	if storage.closed {
+		return n, io.ErrClosedPipe
+	}
+	if storage.file == nil {
+		storage.file, err = tmpfile.New(storage.directory, "piece")
+		if err != nil {
+			return n, err
+		}
+	}
+
+	nn, err := storage.file.WriteAt(p, off-int64(len(storage.memory)))
+	return n + nn, err
+}
+
+func (storage *spillStorage) Close() error {
+	storage.closed = true
+	if storage.file == nil {
+		return nil
+	}
+	err := storage.file.Close()
+	storage.file = nil
+	return err
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package eestream_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vivint/infectious"
+
+	"storj.io/common/memory"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/uplink/private/eestream"
+)
+
+func TestSpill(t *testing.T) {
+	ctx := testcontext.New(t)
+
+	fc, err := infectious.NewFEC(2, 4)
+	require.NoError(t, err)
+	es := eestream.NewRSScheme(fc, 1024)
+	rs, err := eestream.NewRedundancyStrategy(es, 0, 0)
+	require.NoError(t, err)
+
+	data := testrand.Bytes(64 * memory.KiB)
+
+	for _, threshold := range []int64{0, 1, 1000, 16 * memory.KiB.Int64(), memory.MiB.Int64()} {
+		threshold := threshold
+		t.Run(fmt.Sprint(threshold), func(t *testing.T) {
+			spillCtx := eestream.WithSpill(ctx, eestream.SpillConfig{
+				Directory:       ctx.Dir("spill"),
+				MemoryThreshold: threshold,
+			})
+
+			readers, err := eestream.EncodeReader2(spillCtx, bytes.NewReader(data), rs)
+			require.NoError(t, err)
+
+			// the encoding continues when one of the readers is closed early.
+			require.NoError(t, readers[0].Close())
+
+			readerMap := make(map[int]io.ReadCloser, len(readers))
+			for i, reader := range readers[1:] {
+				readerMap[i+1] = reader
+			}
+
+			decodeCtx, cancel := context.WithCancel(spillCtx)
+			decoder := eestream.DecodeReaders2(decodeCtx, cancel, readerMap, rs, int64(len(data)), 32*memory.KiB.Int(), false)
+			decoded, err := ioutil.ReadAll(decoder)
+			require.NoError(t, err)
+			require.NoError(t, decoder.Close())
+			require.Equal(t, data, decoded)
+		})
+	}
+}
@@ -30,11 +30,22 @@ type StripeReader struct {
 	inmap               map[int][]byte
 	errmap              map[int]error
 	forceErrorDetection bool
+	scheduler           *pieceScheduler
+	// corrected, when not nil, is called with the numbers of the readers,
+	// whose erasure shares were corrected.
+	corrected func(piece int)
 }
 
 // NewStripeReader creates a new StripeReader from the given readers, erasure
 // scheme and max buffer memory.
 func NewStripeReader(rs map[int]io.ReadCloser, es ErasureScheme, mbm int, forceErrorDetection bool) *StripeReader {
+	return newStripeReader(rs, es, mbm, forceErrorDetection, SpillConfig{}, nil)
+}
+
+// newStripeReader creates a new StripeReader, whose buffers are larger than
+// the memory threshold of spill store the rest of their data in temporary files.
+// The scheduler, when not nil, is notified about the failed and slow readers.
+func newStripeReader(rs map[int]io.ReadCloser, es ErasureScheme, mbm int, forceErrorDetection bool, spill SpillConfig, scheduler *pieceScheduler) *StripeReader {
 	readerCount := len(rs)
 
 	r := &StripeReader{
@@ -46,17 +57,18 @@ func NewStripeReader(rs map[int]io.ReadCloser, es ErasureScheme, mbm int, forceE
 		inmap:               make(map[int][]byte, readerCount),
 		errmap:              make(map[int]error, readerCount),
 		forceErrorDetection: forceErrorDetection,
+		scheduler:           scheduler,
 	}
 
-	bufSize := mbm / readerCount
-	bufSize -= bufSize % es.ErasureShareSize()
-	if bufSize < es.ErasureShareSize() {
-		bufSize = es.ErasureShareSize()
-	}
+	bufSize := pieceBufferSize(es, readerCount, mbm)
 
 	for i := range rs {
 		r.inbufs[i] = make([]byte, es.ErasureShareSize())
-		r.bufs[i] = NewPieceBuffer(make([]byte, bufSize), es.ErasureShareSize(), r.cond)
+		if spill.Enabled() && int64(bufSize) > spill.MemoryThreshold {
+			r.bufs[i] = newPieceBuffer(newSpillStorage(spill), bufSize, es.ErasureShareSize(), r.cond)
+		} else {
+			r.bufs[i] = NewPieceBuffer(make([]byte, bufSize), es.ErasureShareSize(), r.cond)
+		}
 		// Kick off a goroutine each reader to be copied into a PieceBuffer.
 		go func(r io.Reader, buf *PieceBuffer) {
 			_, err := io.Copy(buf, r)
@@ -71,6 +83,31 @@ func NewStripeReader(rs map[int]io.ReadCloser, es ErasureScheme, mbm int, forceE
 	return r
 }
 
+// pieceBufferSize returns the size of the buffer for each of readerCount
+// pieces, when at most mbm bytes should be used.
+func pieceBufferSize(es ErasureScheme, readerCount int, mbm int) int {
+	bufSize := mbm / readerCount
+	bufSize -= bufSize % es.ErasureShareSize()
+	if bufSize < es.ErasureShareSize() {
+		bufSize = es.ErasureShareSize()
+	}
+	return bufSize
+}
+
+// DecodeMemory returns the number of bytes kept in memory for decoding
+// readerCount pieces with the max buffer memory mbm and the spill
+// configuration spill.
+func DecodeMemory(es ErasureScheme, readerCount int, mbm int, spill SpillConfig) int64 {
+	if readerCount <= 0 {
+		return 0
+	}
+	bufSize := int64(pieceBufferSize(es, readerCount, mbm))
+	if spill.Enabled() && bufSize > spill.MemoryThreshold {
+		bufSize = spill.MemoryThreshold
+	}
+	return int64(readerCount)*(bufSize+int64(es.ErasureShareSize())) + int64(es.StripeSize())
+}
+
 // Close closes the StripeReader and all PieceBuffers.
 func (r *StripeReader) Close() error {
 	errs := make(chan error, len(r.bufs))
@@ -103,18 +140,19 @@ func (r *StripeReader) ReadStripe(ctx context.Context, num int64, p []byte) (_ [
 	r.cond.L.Lock()
 	defer r.cond.L.Unlock()
 
-	for r.pendingReaders() {
+	for r.pendingReaders() || r.scheduler.startSpare() {
 		for r.readAvailableShares(ctx, num) == 0 {
 			r.cond.Wait()
 		}
 		if r.hasEnoughShares() {
-			out, err := r.scheme.Decode(p, r.inmap)
+			out, err := r.decode(p)
 			if err != nil {
 				if r.shouldWaitForMore(err) {
 					continue
 				}
 				return nil, err
 			}
+			r.cancelSlow()
 			return out, nil
 		}
 	}
@@ -123,6 +161,20 @@ func (r *StripeReader) ReadStripe(ctx context.Context, num int64, p []byte) (_ [
 	return nil, r.combineErrs(num)
 }
 
+// decode decodes the erasure shares in inmap and reports the corrected ones.
+func (r *StripeReader) decode(p []byte) ([]byte, error) {
+	if r.corrected != nil {
+		if scheme, ok := correctingSchemeOf(r.scheme); ok {
+			out, corrected, err := scheme.DecodeCorrected(p, r.inmap)
+			for _, num := range corrected {
+				r.corrected(num)
+			}
+			return out, err
+		}
+	}
+	return r.scheme.Decode(p, r.inmap)
+}
+
 // readAvailableShares reads the available num-th erasure shares from the piece
 // buffers without blocking. The return value n is the number of erasure shares
 // read.
@@ -135,12 +187,14 @@ func (r *StripeReader) readAvailableShares(ctx context.Context, num int64) (n in
 		hasShare, err := buf.HasShare(num)
 		if err != nil {
 			r.errmap[i] = err
+			r.scheduler.failed(i)
 			continue
 		}
 		if hasShare {
 			err := buf.ReadShare(num, r.inbufs[i])
 			if err != nil {
 				r.errmap[i] = err
+				r.scheduler.failed(i)
 			} else {
 				r.inmap[i] = r.inbufs[i]
 			}
@@ -151,8 +205,9 @@ func (r *StripeReader) readAvailableShares(ctx context.Context, num int64) (n in
 }
 
 // pendingReaders checks if there are any pending readers to get a share from.
+// The readers, which haven't been started by the scheduler, aren't pending.
 func (r *StripeReader) pendingReaders() bool {
-	goodReaders := r.readerCount - len(r.errmap)
+	goodReaders := r.readerCount - len(r.errmap) - r.scheduler.waiting()
 	return goodReaders >= r.scheme.RequiredCount() && goodReaders > len(r.inmap)
 }
 
@@ -172,7 +227,20 @@ func (r *StripeReader) shouldWaitForMore(err error) bool {
 		return false
 	}
 	// check if there are more input buffers to wait for
-	return r.pendingReaders()
+	return r.pendingReaders() || r.scheduler.startSpare()
+}
+
+// cancelSlow cancels the readers, which haven't delivered their erasure
+// share of the decoded stripe, when the scheduler is configured to do so.
+func (r *StripeReader) cancelSlow() {
+	if !r.scheduler.cancelsSlow() {
+		return
+	}
+	for i := range r.bufs {
+		if r.inmap[i] == nil && r.errmap[i] == nil {
+			r.scheduler.cancelPiece(i)
+		}
+	}
 }
 
 // combineErrs makes a useful error message from the errors in errmap.
@@ -186,5 +254,5 @@ func (r *StripeReader) combineErrs(num int64) error {
 		errstrings = append(errstrings, fmt.Sprintf("\nerror retrieving piece %02d: %v", i, err))
 	}
 	sort.Strings(errstrings)
-	return Error.New("failed to download stripe %d: %s", num, strings.Join(errstrings, ""))
+	return Error.Wrap(ErrNotEnoughPieces.New("failed to download stripe %d: %s", num, strings.Join(errstrings, "")))
 }
@@ -0,0 +1,39 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package eestream
+
+import "context"
+
+// correctingScheme is an ErasureScheme, which reports the erasure shares it
+// corrected while decoding.
+type correctingScheme interface {
+	DecodeCorrected(out []byte, in map[int][]byte) (_ []byte, corrected []int, err error)
+}
+
+// correctingSchemeOf returns es as a correctingScheme, if it supports it.
+func correctingSchemeOf(es ErasureScheme) (correctingScheme, bool) {
+	if rs, ok := es.(RedundancyStrategy); ok {
+		es = rs.ErasureScheme
+	}
+	scheme, ok := es.(correctingScheme)
+	return scheme, ok
+}
+
+// The key type is unexported to prevent collisions with context keys defined in
+// other packages.
+type verificationKey struct{}
+
+// WithVerification returns a context, whose decoding always uses one more
+// erasure share than required, so that corrupted erasure shares are detected
+// and corrected. The numbers of the pieces, whose erasure shares were
+// corrected, are reported to corrected, possibly concurrently.
+func WithVerification(ctx context.Context, corrected func(piece int)) context.Context {
+	return context.WithValue(ctx, verificationKey{}, corrected)
+}
+
+// verificationFromContext returns the function set with WithVerification.
+func verificationFromContext(ctx context.Context) (corrected func(piece int), ok bool) {
+	corrected, ok = ctx.Value(verificationKey{}).(func(piece int))
+	return corrected, ok
+}
@@ -0,0 +1,68 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package eestream_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/vivint/infectious"
+
+	"storj.io/common/memory"
+	"storj.io/common/ranger"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/uplink/private/eestream"
+)
+
+func TestVerification(t *testing.T) {
+	ctx := testcontext.New(t)
+
+	fc, err := infectious.NewFEC(2, 5)
+	require.NoError(t, err)
+	es := eestream.NewRSScheme(fc, 1024)
+	rs, err := eestream.NewRedundancyStrategy(es, 0, 0)
+	require.NoError(t, err)
+
+	data := testrand.Bytes(64 * memory.KiB)
+	readers, err := eestream.EncodeReader2(ctx, bytes.NewReader(data), rs)
+	require.NoError(t, err)
+	pieces, err := readAll(readers)
+	require.NoError(t, err)
+
+	// corrupt all erasure shares of piece 3
+	for i := 0; i < len(pieces[3]); i += es.ErasureShareSize() {
+		pieces[3][i] ^= 0xFF
+	}
+
+	rrs := make(map[int]ranger.Ranger, len(pieces))
+	for i, piece := range pieces {
+		rrs[i] = ranger.ByteRanger(piece)
+	}
+	rr, err := eestream.Decode(rrs, rs, 0, false)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	corrected := map[int]int{}
+	verifyCtx := eestream.WithVerification(ctx, func(piece int) {
+		mu.Lock()
+		defer mu.Unlock()
+		corrected[piece]++
+	})
+
+	reader, err := rr.Range(eestream.WithLongTail(verifyCtx, eestream.LongTail{ExtraPieces: -1}), 0, rr.Size())
+	require.NoError(t, err)
+	decoded, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	require.Equal(t, data, decoded)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, corrected, 1)
+	require.Positive(t, corrected[3])
+}
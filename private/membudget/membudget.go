@@ -0,0 +1,144 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package membudget implements a memory budget shared by transfers.
+package membudget
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrExhausted is returned by Acquire of a fail-fast budget, when there isn't
+// enough memory left.
+var ErrExhausted = errors.New("memory budget exhausted")
+
+// Budget limits the memory reserved by concurrent transfers.
+//
+// The methods are safe for concurrent use and a nil Budget doesn't limit
+// anything.
+type Budget struct {
+	limit    int64
+	failFast bool
+
+	mu   sync.Mutex
+	used int64
+	// released is closed and replaced every time memory is released.
+	released chan struct{}
+}
+
+// New returns a budget of limit bytes. When failFast is set Acquire fails
+// instead of waiting for memory to be released. It returns nil when limit
+// isn't positive.
+func New(limit int64, failFast bool) *Budget {
+	if limit <= 0 {
+		return nil
+	}
+	return &Budget{
+		limit:    limit,
+		failFast: failFast,
+		released: make(chan struct{}),
+	}
+}
+
+// Acquire reserves n bytes. It waits until they are available or returns
+// ErrExhausted for a fail-fast budget or a context returned by WithFailFast.
+// Reservations larger than the limit
+// reserve the whole budget. The returned function releases the reservation
+// and can be called multiple times.
+func (budget *Budget) Acquire(ctx context.Context, n int64) (release func(), err error) {
+	if budget == nil {
+		return func() {}, nil
+	}
+	if n > budget.limit {
+		n = budget.limit
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		budget.mu.Lock()
+		if budget.used+n <= budget.limit {
+			budget.used += n
+			budget.mu.Unlock()
+
+			var once sync.Once
+			return func() { once.Do(func() { budget.release(n) }) }, nil
+		}
+		released := budget.released
+		budget.mu.Unlock()
+
+		if budget.failFast || isFailFast(ctx) {
+			return nil, ErrExhausted
+		}
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (budget *Budget) release(n int64) {
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+
+	budget.used -= n
+	close(budget.released)
+	budget.released = make(chan struct{})
+}
+
+// Used returns the number of currently reserved bytes.
+func (budget *Budget) Used() int64 {
+	if budget == nil {
+		return 0
+	}
+
+	budget.mu.Lock()
+	defer budget.mu.Unlock()
+	return budget.used
+}
+
+// Limit returns the size of the budget. It's zero for a nil budget.
+func (budget *Budget) Limit() int64 {
+	if budget == nil {
+		return 0
+	}
+	return budget.limit
+}
+
+// The key type is unexported to prevent collisions with context keys defined in
+// other packages.
+type reservedKey struct{}
+
+// WithReserved returns a context, whose transfers don't reserve memory from
+// the budget, because the caller has already reserved it for them.
+func WithReserved(ctx context.Context) context.Context {
+	return context.WithValue(ctx, reservedKey{}, true)
+}
+
+// IsReserved returns whether the memory of the transfers has been reserved
+// with WithReserved.
+func IsReserved(ctx context.Context) bool {
+	reserved, _ := ctx.Value(reservedKey{}).(bool)
+	return reserved
+}
+
+// The key type is unexported to prevent collisions with context keys defined in
+// other packages.
+type failFastKey struct{}
+
+// WithFailFast returns a context, whose transfers don't wait for memory to be
+// released, when there isn't enough of it left, but fail with ErrExhausted.
+func WithFailFast(ctx context.Context) context.Context {
+	return context.WithValue(ctx, failFastKey{}, true)
+}
+
+func isFailFast(ctx context.Context) bool {
+	failFast, _ := ctx.Value(failFastKey{}).(bool)
+	return failFast
+}
@@ -0,0 +1,96 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package membudget_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/uplink/private/membudget"
+)
+
+func TestBudget(t *testing.T) {
+	ctx := context.Background()
+
+	require.Nil(t, membudget.New(0, false))
+
+	var unlimited *membudget.Budget
+	release, err := unlimited.Acquire(ctx, 1<<40)
+	require.NoError(t, err)
+	release()
+	require.Zero(t, unlimited.Used())
+
+	budget := membudget.New(100, false)
+	first, err := budget.Acquire(ctx, 60)
+	require.NoError(t, err)
+	require.EqualValues(t, 60, budget.Used())
+
+	acquired := make(chan error, 1)
+	go func() {
+		release, err := budget.Acquire(ctx, 60)
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired more than the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first()
+	first() // releasing twice doesn't change anything
+	require.NoError(t, <-acquired)
+	require.Zero(t, budget.Used())
+
+	// reservations larger than the limit take the whole budget
+	all, err := budget.Acquire(ctx, 1000)
+	require.NoError(t, err)
+	require.EqualValues(t, 100, budget.Used())
+
+	canceledCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = budget.Acquire(canceledCtx, 1)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	all()
+	require.Zero(t, budget.Used())
+}
+
+func TestBudgetFailFast(t *testing.T) {
+	ctx := context.Background()
+
+	budget := membudget.New(100, true)
+	release, err := budget.Acquire(ctx, 80)
+	require.NoError(t, err)
+
+	_, err = budget.Acquire(ctx, 30)
+	require.True(t, errors.Is(err, membudget.ErrExhausted))
+
+	release()
+	release, err = budget.Acquire(ctx, 30)
+	require.NoError(t, err)
+	release()
+
+	// a waiting budget fails fast with the context
+	budget = membudget.New(100, false)
+	release, err = budget.Acquire(ctx, 80)
+	require.NoError(t, err)
+
+	_, err = budget.Acquire(membudget.WithFailFast(ctx), 30)
+	require.True(t, errors.Is(err, membudget.ErrExhausted))
+	release()
+}
+
+func TestReserved(t *testing.T) {
+	ctx := context.Background()
+	require.False(t, membudget.IsReserved(ctx))
+	require.True(t, membudget.IsReserved(membudget.WithReserved(ctx)))
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"crypto/rand"
 	"errors"
+	"sort"
 	"strings"
 	"time"
 
@@ -18,6 +19,11 @@ import (
 
 var contentTypeKey = "content-type"
 
+// SystemMetadataPrefix is the prefix of the keys in the object metadata, which
+// are reserved for information managed by uplink itself. It cannot collide with
+// custom metadata, because custom metadata keys must not contain 0 bytes.
+const SystemMetadataPrefix = "\x00uplink:"
+
 // Meta info about a segment.
 type Meta struct {
 	Modified   time.Time
@@ -93,6 +99,14 @@ func (db *DB) ModifyObject(ctx context.Context, bucket, key string) (object *Mut
 func (db *DB) UpdateObjectMetadata(ctx context.Context, bucket, key string, newMetadata map[string]string) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
+	return db.UpdateObjectMetadataWithPrecondition(ctx, bucket, key, newMetadata, nil)
+}
+
+// UpdateObjectMetadataWithPrecondition replaces the custom metadata like
+// UpdateObjectMetadata, when the object matches the precondition.
+func (db *DB) UpdateObjectMetadataWithPrecondition(ctx context.Context, bucket, key string, newMetadata map[string]string, precondition *Precondition) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
 	if bucket == "" {
 		return ErrNoBucket.New("")
 	}
@@ -122,13 +136,30 @@ func (db *DB) UpdateObjectMetadata(ctx context.Context, bucket, key string, newM
 		return err
 	}
 
+	if err := precondition.Check(objectInfo.Version, objectInfo.StreamID); err != nil {
+		return err
+	}
+
 	object, err := db.objectFromRawObjectItem(ctx, bucket, key, objectInfo)
 	if err != nil {
 		return err
 	}
 
+	// keep the system metadata, which isn't part of the custom metadata
+	userDefined := make(map[string]string, len(newMetadata))
+	for k, v := range newMetadata {
+		if !strings.HasPrefix(k, SystemMetadataPrefix) {
+			userDefined[k] = v
+		}
+	}
+	for k, v := range object.Metadata {
+		if strings.HasPrefix(k, SystemMetadataPrefix) {
+			userDefined[k] = v
+		}
+	}
+
 	metadataBytes, err := pb.Marshal(&pb.SerializableMeta{
-		UserDefined: newMetadata,
+		UserDefined: userDefined,
 	})
 	if err != nil {
 		return err
@@ -182,7 +213,7 @@ func (db *DB) UpdateObjectMetadata(ctx context.Context, bucket, key string, newM
 		return err
 	}
 
-	return db.metainfo.UpdateObjectMetadata(ctx, UpdateObjectMetadataParams{
+	err = db.metainfo.UpdateObjectMetadata(ctx, UpdateObjectMetadataParams{
 		Bucket:                        []byte(bucket),
 		EncryptedObjectKey:            []byte(encPath.Raw()),
 		Version:                       int32(object.Version),
@@ -191,12 +222,30 @@ func (db *DB) UpdateObjectMetadata(ctx context.Context, bucket, key string, newM
 		EncryptedMetadataEncryptedKey: encryptedKey,
 		EncryptedMetadataNonce:        encryptedKeyNonce,
 	})
+	// the satellite updates only the stream, which has been checked, so it's
+	// not found, when the object has been replaced in the meantime.
+	if precondition != nil && ErrObjectNotFound.Has(err) {
+		return ErrPreconditionFailed.Wrap(err)
+	}
+	return err
 }
 
 // DeleteObject deletes an object from database.
 func (db *DB) DeleteObject(ctx context.Context, bucket, key string) (_ Object, err error) {
 	defer mon.Task()(&ctx)(&err)
 
+	return db.DeleteObjectWithPrecondition(ctx, bucket, key, nil)
+}
+
+// DeleteObjectWithPrecondition deletes an object from database like
+// DeleteObject, when it matches the precondition.
+//
+// The satellite always deletes the latest version of the object, so the
+// precondition is checked before the deletion. The deleted object is compared
+// again, so that it's reported, when the object was replaced in the meantime.
+func (db *DB) DeleteObjectWithPrecondition(ctx context.Context, bucket, key string, precondition *Precondition) (_ Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
 	if bucket == "" {
 		return Object{}, ErrNoBucket.New("")
 	}
@@ -210,6 +259,19 @@ func (db *DB) DeleteObject(ctx context.Context, bucket, key string) (_ Object, e
 		return Object{}, err
 	}
 
+	if precondition != nil {
+		current, err := db.metainfo.GetObject(ctx, GetObjectParams{
+			Bucket:        []byte(bucket),
+			EncryptedPath: []byte(encPath.Raw()),
+		})
+		if err != nil {
+			return Object{}, err
+		}
+		if err := precondition.Check(current.Version, current.StreamID); err != nil {
+			return Object{}, err
+		}
+	}
+
 	object, err := db.metainfo.BeginDeleteObject(ctx, BeginDeleteObjectParams{
 		Bucket:        []byte(bucket),
 		EncryptedPath: []byte(encPath.Raw()),
@@ -218,7 +280,16 @@ func (db *DB) DeleteObject(ctx context.Context, bucket, key string) (_ Object, e
 		return Object{}, err
 	}
 
-	return db.objectFromRawObjectItem(ctx, bucket, key, object)
+	deleted, err := db.objectFromRawObjectItem(ctx, bucket, key, object)
+	if err != nil {
+		return Object{}, err
+	}
+	if object.Bucket != "" { // the deleted object is returned only with read or list permission
+		if err := precondition.Check(object.Version, object.StreamID); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
 }
 
 // ModifyPendingObject creates an interface for updating a partially uploaded object.
@@ -329,6 +400,8 @@ func (db *DB) ListObjects(ctx context.Context, bucket string, options ListOption
 	case After:
 		// after lists forwards from cursor, without cursor
 		startAfter = options.Cursor
+	case Before:
+		// before lists backwards from cursor, without cursor
 	default:
 		return ObjectList{}, errClass.New("invalid direction %d", options.Direction)
 	}
@@ -351,6 +424,10 @@ func (db *DB) ListObjects(ctx context.Context, bucket string, options ListOption
 		return ObjectList{}, errClass.Wrap(err)
 	}
 
+	if options.Direction == Before {
+		return db.listObjectsBefore(ctx, bucket, options, pi)
+	}
+
 	startAfter, err = encryption.EncryptPathRaw(startAfter, pi.Cipher, &pi.ParentKey)
 	if err != nil {
 		return ObjectList{}, errClass.Wrap(err)
@@ -383,6 +460,140 @@ func (db *DB) ListObjects(ctx context.Context, bucket string, options ListOption
 	}, nil
 }
 
+// listObjectsBefore lists the objects before the cursor in reverse order, or
+// the last objects, when the cursor is empty. The satellite lists only
+// forwards in the order of the encrypted keys, so the first page is listed
+// from the start of the prefix. The following pages are listed from the
+// cursors of the forward pages kept in options.Pages. Without a limit the
+// page size of the satellite is used.
+func (db *DB) listObjectsBefore(ctx context.Context, bucket string, options ListOptions, pi *encryption.PrefixInfo) (list ObjectList, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	var before string
+	if options.Cursor != "" {
+		before, err = encryptCursor(options.Cursor, pi)
+		if err != nil {
+			return ObjectList{}, errClass.Wrap(err)
+		}
+	}
+
+	if options.Pages == nil {
+		options.Pages = &ListPages{}
+	}
+	pages := options.Pages
+
+	// the pages ending before the cursor are followed by the page containing
+	// it, so listing from the last one of them lists at least a full page of
+	// objects before the cursor.
+	page := sort.Search(len(pages.cursors), func(i int) bool {
+		return before != "" && string(pages.cursors[i]) >= before
+	}) - 1
+	if page < 0 {
+		page = 0
+	}
+
+	kept, more, err := db.listPagesBefore(ctx, bucket, options, pi, before, page)
+	if err != nil {
+		return ObjectList{}, err
+	}
+	if page > 0 && len(kept) < pages.limit {
+		// objects have been deleted since the pages were listed.
+		kept, more, err = db.listPagesBefore(ctx, bucket, options, pi, before, 0)
+		if err != nil {
+			return ObjectList{}, err
+		}
+	}
+
+	for i, k := 0, len(kept)-1; i < k; i, k = i+1, k-1 {
+		kept[i], kept[k] = kept[k], kept[i]
+	}
+
+	objectsList, err := db.objectsFromRawObjectList(ctx, kept, pi, "")
+	if err != nil {
+		return ObjectList{}, errClass.Wrap(err)
+	}
+
+	return ObjectList{
+		Bucket: bucket,
+		Prefix: options.Prefix,
+		More:   more,
+		Items:  objectsList,
+	}, nil
+}
+
+// listPagesBefore lists the forward pages starting with page up to the
+// cursor and keeps the last full page of the objects before it. The cursors
+// of the pages listed for the first time are added to options.Pages.
+func (db *DB) listPagesBefore(ctx context.Context, bucket string, options ListOptions, pi *encryption.PrefixInfo, before string, page int) (kept []RawObjectListItem, more bool, err error) {
+	pages := options.Pages
+	if pages.limit <= 0 {
+		pages.limit = options.Limit
+	}
+
+	var cursor []byte
+	if page > 0 {
+		cursor = pages.cursors[page-1]
+		// the objects of the previous pages are before the kept ones.
+		more = true
+	}
+
+	for ; ; page++ {
+		items, hasMore, err := db.metainfo.ListObjects(ctx, ListObjectsParams{
+			Bucket:                []byte(bucket),
+			EncryptedPrefix:       []byte(pi.ParentEnc.Raw()),
+			EncryptedCursor:       cursor,
+			Limit:                 int32(options.Limit),
+			IncludeCustomMetadata: options.IncludeCustomMetadata,
+			IncludeSystemMetadata: options.IncludeSystemMetadata,
+			Recursive:             options.Recursive,
+			Status:                options.Status,
+		})
+		if err != nil {
+			return nil, false, errClass.Wrap(err)
+		}
+		if pages.limit <= 0 {
+			pages.limit = len(items)
+		}
+
+		reachedCursor := false
+		for _, item := range items {
+			if before != "" && string(item.EncryptedPath) >= before {
+				reachedCursor = true
+				break
+			}
+			kept = append(kept, item)
+		}
+		if len(kept) > pages.limit {
+			more = true
+			kept = append([]RawObjectListItem(nil), kept[len(kept)-pages.limit:]...)
+		}
+
+		if !hasMore || len(items) == 0 {
+			break
+		}
+		cursor = items[len(items)-1].EncryptedPath
+		if page == len(pages.cursors) {
+			pages.cursors = append(pages.cursors, cursor)
+		}
+		if reachedCursor {
+			break
+		}
+	}
+
+	return kept, more, nil
+}
+
+// encryptCursor encrypts the cursor relative to the prefix, so that it can
+// be compared with the encrypted keys of the listed objects. The cursor of
+// a collapsed prefix ends with a slash, which isn't encrypted.
+func encryptCursor(cursor string, pi *encryption.PrefixInfo) (string, error) {
+	if trimmed := strings.TrimSuffix(cursor, "/"); trimmed != cursor {
+		encrypted, err := encryption.EncryptPathRaw(trimmed, pi.Cipher, &pi.ParentKey)
+		return encrypted + "/", err
+	}
+	return encryption.EncryptPathRaw(cursor, pi.Cipher, &pi.ParentKey)
+}
+
 func (db *DB) objectsFromRawObjectList(ctx context.Context, items []RawObjectListItem, pi *encryption.PrefixInfo, startAfter string) (objectList []Object, err error) {
 	objectList = make([]Object, 0, len(items))
 
@@ -493,6 +704,18 @@ func (db *DB) ListSegments(ctx context.Context, params ListSegmentsParams) (resp
 func (db *DB) GetObject(ctx context.Context, bucket, key string) (info Object, err error) {
 	defer mon.Task()(&ctx)(&err)
 
+	return db.getObject(ctx, bucket, key, 0)
+}
+
+// GetObjectVersion returns information about the specific version of an
+// object. Zero version returns the latest one.
+func (db *DB) GetObjectVersion(ctx context.Context, bucket, key string, version uint32) (info Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	return db.getObject(ctx, bucket, key, version)
+}
+
+func (db *DB) getObject(ctx context.Context, bucket, key string, version uint32) (info Object, err error) {
 	if bucket == "" {
 		return Object{}, ErrNoBucket.New("")
 	}
@@ -509,11 +732,16 @@ func (db *DB) GetObject(ctx context.Context, bucket, key string) (info Object, e
 	objectInfo, err := db.metainfo.GetObject(ctx, GetObjectParams{
 		Bucket:                     []byte(bucket),
 		EncryptedPath:              []byte(encPath.Raw()),
+		Version:                    int32(version),
 		RedundancySchemePerSegment: true,
 	})
 	if err != nil {
 		return Object{}, err
 	}
+	// the satellite keeps only the latest committed version of an object.
+	if version != 0 && objectInfo.Version != version {
+		return Object{}, ErrObjectNotFound.New("version %d", version)
+	}
 
 	return db.objectFromRawObjectItem(ctx, bucket, key, objectInfo)
 }
@@ -4,8 +4,13 @@
 package metaclient
 
 import (
+	"bytes"
+	"fmt"
 	"time"
 
+	"github.com/zeebo/errs"
+
+	"storj.io/common/pb"
 	"storj.io/common/storj"
 )
 
@@ -71,8 +76,70 @@ var (
 
 	// ErrObjectNotFound is an error class for non-existing object.
 	ErrObjectNotFound = storj.ErrObjectNotFound
+
+	// ErrPreconditionFailed is an error class for objects, which don't match
+	// the precondition of an operation.
+	ErrPreconditionFailed = errs.Class("precondition failed")
 )
 
+// Precondition is the expected state of an object, which is modified. The
+// zero fields aren't checked.
+type Precondition struct {
+	Version  uint32
+	StreamID storj.StreamID
+}
+
+// Check returns an error, when the object with the version and stream ID
+// doesn't match the precondition. A nil precondition matches any object.
+func (precondition *Precondition) Check(version uint32, streamID storj.StreamID) error {
+	if precondition == nil {
+		return nil
+	}
+	if precondition.Version != 0 && precondition.Version != version {
+		return ErrPreconditionFailed.New("expected version %d, got %d", precondition.Version, version)
+	}
+	if len(precondition.StreamID) > 0 && !sameStream(precondition.StreamID, streamID) {
+		return ErrPreconditionFailed.New("stream ID doesn't match")
+	}
+	return nil
+}
+
+// CheckStreamID returns an error, when the object identified by the stream ID
+// doesn't match the precondition. The version is the one packed in the stream
+// ID by the satellite.
+func (precondition *Precondition) CheckStreamID(streamID storj.StreamID) error {
+	if precondition == nil {
+		return nil
+	}
+	var packed packedStreamID
+	if err := pb.Unmarshal(streamID, &packed); err != nil {
+		return errClass.Wrap(err)
+	}
+	return precondition.Check(uint32(packed.Version), streamID)
+}
+
+// packedStreamID contains the fields of the stream ID packed by the satellite,
+// which identify the stream of an object. The other fields, like the creation
+// date and the signature, depend on the request returning the stream ID.
+type packedStreamID struct {
+	Version  int32  `protobuf:"varint,3,opt,name=version,proto3"`
+	StreamID []byte `protobuf:"bytes,10,opt,name=stream_id,json=streamId,proto3"`
+}
+
+func (packed *packedStreamID) Reset()         { *packed = packedStreamID{} }
+func (packed *packedStreamID) String() string { return fmt.Sprintf("%+v", *packed) }
+func (packed *packedStreamID) ProtoMessage()  {}
+
+// sameStream returns whether the packed stream IDs identify the same stream.
+// The stream IDs, which can't be unpacked, are compared as they are.
+func sameStream(a, b storj.StreamID) bool {
+	var packedA, packedB packedStreamID
+	if pb.Unmarshal(a, &packedA) != nil || pb.Unmarshal(b, &packedB) != nil || len(packedA.StreamID) == 0 {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(packedA.StreamID, packedB.StreamID)
+}
+
 // Object contains information about a specific object.
 type Object = storj.Object
 
@@ -109,7 +176,9 @@ type Bucket = storj.Bucket
 type ListDirection = storj.ListDirection
 
 const (
-	// Before lists backwards from cursor, without cursor [NOT SUPPORTED].
+	// Before lists backwards from cursor, without cursor. It's supported only
+	// for listing objects. The satellite lists only forwards, so all objects
+	// from the start of the prefix up to the cursor are listed.
 	Before = storj.Before
 	// Backward lists backwards from cursor, including cursor [NOT SUPPORTED].
 	Backward = storj.Backward
@@ -130,6 +199,21 @@ type ListOptions struct {
 	IncludeCustomMetadata bool
 	IncludeSystemMetadata bool
 	Status                int32
+
+	// Pages, when listing Before, keeps the forward pages listed from the
+	// satellite. It's shared with the options of the following pages, so
+	// that they aren't listed from the start of the prefix again.
+	Pages *ListPages
+}
+
+// ListPages keeps the cursors of the forward pages listed from the satellite,
+// while listing objects Before a cursor.
+type ListPages struct {
+	// limit is the number of objects of a full page.
+	limit int
+	// cursors are the encrypted keys of the last objects of the pages listed
+	// from the start of the prefix.
+	cursors [][]byte
 }
 
 // NextPage returns options for listing the next page.
@@ -138,13 +222,22 @@ func (opts ListOptions) NextPage(list ObjectList) ListOptions {
 		return ListOptions{}
 	}
 
+	direction := After
+	if opts.Direction == Before {
+		direction = Before
+	}
+
 	return ListOptions{
-		Prefix:    opts.Prefix,
-		Cursor:    list.Items[len(list.Items)-1].Path,
-		Delimiter: opts.Delimiter,
-		Recursive: opts.Recursive,
-		Direction: After,
-		Limit:     opts.Limit,
+		Prefix:                opts.Prefix,
+		Cursor:                list.Items[len(list.Items)-1].Path,
+		Delimiter:             opts.Delimiter,
+		Recursive:             opts.Recursive,
+		Direction:             direction,
+		Limit:                 opts.Limit,
+		IncludeCustomMetadata: opts.IncludeCustomMetadata,
+		IncludeSystemMetadata: opts.IncludeSystemMetadata,
+		Status:                opts.Status,
+		Pages:                 opts.Pages,
 	}
 }
 
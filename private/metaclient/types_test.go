@@ -5,9 +5,13 @@ package metaclient_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
+	"storj.io/common/pb"
+	"storj.io/common/storj"
+	"storj.io/common/testrand"
 	"storj.io/uplink/private/metaclient"
 )
 
@@ -19,6 +23,9 @@ func TestListOptions(t *testing.T) {
 		Recursive: true,
 		Direction: metaclient.After,
 		Limit:     30,
+
+		IncludeCustomMetadata: true,
+		IncludeSystemMetadata: true,
 	}
 
 	list := metaclient.ObjectList{
@@ -38,5 +45,41 @@ func TestListOptions(t *testing.T) {
 		Recursive: true,
 		Direction: metaclient.After,
 		Limit:     30,
+
+		IncludeCustomMetadata: true,
+		IncludeSystemMetadata: true,
 	}, newopts)
 }
+
+func TestPrecondition(t *testing.T) {
+	// pack returns a stream ID packed like by the satellite. The creation
+	// date differs between the requests returning the same stream.
+	pack := func(version byte, streamID []byte, created time.Time) storj.StreamID {
+		packed, err := pb.Marshal(&pb.SatStreamID{CreationDate: created})
+		require.NoError(t, err)
+		packed = append(packed, 0x18, version)
+		packed = append(packed, 0x52, byte(len(streamID)))
+		return append(packed, streamID...)
+	}
+
+	stream := testrand.BytesInt(16)
+	other := testrand.BytesInt(16)
+
+	fromGet := pack(1, stream, time.Now())
+	fromMove := pack(1, stream, time.Time{})
+
+	var nilPrecondition *metaclient.Precondition
+	require.NoError(t, nilPrecondition.Check(2, fromGet))
+	require.NoError(t, nilPrecondition.CheckStreamID(fromMove))
+
+	precondition := &metaclient.Precondition{Version: 1, StreamID: fromGet}
+	require.NoError(t, precondition.Check(1, fromGet))
+	require.NoError(t, precondition.CheckStreamID(fromMove))
+
+	err := precondition.Check(2, fromGet)
+	require.True(t, metaclient.ErrPreconditionFailed.Has(err))
+	err = precondition.CheckStreamID(pack(2, stream, time.Time{}))
+	require.True(t, metaclient.ErrPreconditionFailed.Has(err))
+	err = precondition.CheckStreamID(pack(1, other, time.Time{}))
+	require.True(t, metaclient.ErrPreconditionFailed.Has(err))
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"io"
 	"sync"
+	"time"
 	_ "unsafe" // for go:linkname
 
 	"github.com/spacemonkeygo/monkit/v3"
@@ -15,10 +16,10 @@ import (
 
 	"storj.io/common/memory"
 	"storj.io/common/ranger"
-	"storj.io/common/storj"
 	"storj.io/common/sync2"
 	"storj.io/uplink"
 	"storj.io/uplink/internal/expose"
+	"storj.io/uplink/private/compression"
 	"storj.io/uplink/private/metaclient"
 	"storj.io/uplink/private/storage/streams"
 )
@@ -73,9 +74,20 @@ type WriterAt interface {
 // DownloadObjectAtOptions options for DownloadObjectAt.
 type DownloadObjectAtOptions struct {
 	Concurrency int
+
+	// Offset and Length select the range of the object, which is downloaded.
+	// When Length is zero or negative, the range ends at the end of the object.
+	Offset int64
+	Length int64
 }
 
 // DownloadObjectAt downloads object into specified io.WriterAt.
+//
+// The data is written at the same offsets as it has in the object, also when
+// only a range of it is downloaded. Before writing, the writer is truncated to
+// the size of the object, so that the data outside of the range is kept, but
+// anything beyond the end of the object is removed. The segments overlapping
+// the range are downloaded in parallel.
 func DownloadObjectAt(ctx context.Context, project *uplink.Project, bucket, key string, writer WriterAt, opts *DownloadObjectAtOptions) (err error) {
 	defer mon.Task()(&ctx)(&err)
 
@@ -89,17 +101,33 @@ func DownloadObjectAt(ctx context.Context, project *uplink.Project, bucket, key
 		return convertKnownErrors(metaclient.ErrNoPath.New(""), bucket, key)
 	}
 
-	// TODO add range support
-	var downloadOpts metaclient.DownloadOptions
-	downloadOpts.Range = metaclient.StreamRange{
-		Mode: metaclient.StreamRangeAll,
-	}
-
 	if opts == nil {
 		opts = &DownloadObjectAtOptions{
 			Concurrency: 2,
 		}
 	}
+	if opts.Offset < 0 {
+		return packageError.New("negative offset %d", opts.Offset)
+	}
+
+	var downloadOpts metaclient.DownloadOptions
+	switch {
+	case opts.Offset == 0 && opts.Length <= 0:
+		downloadOpts.Range = metaclient.StreamRange{
+			Mode: metaclient.StreamRangeAll,
+		}
+	case opts.Length <= 0:
+		downloadOpts.Range = metaclient.StreamRange{
+			Mode:  metaclient.StreamRangeStart,
+			Start: opts.Offset,
+		}
+	default:
+		downloadOpts.Range = metaclient.StreamRange{
+			Mode:  metaclient.StreamRangeStartLimit,
+			Start: opts.Offset,
+			Limit: opts.Offset + opts.Length,
+		}
+	}
 
 	db, err := dialMetainfoDBWithProject(ctx, project)
 	if err != nil {
@@ -116,39 +144,27 @@ func DownloadObjectAt(ctx context.Context, project *uplink.Project, bucket, key
 		return nil
 	}
 
-	// TODO use FixedSegmentSize for old style objects
-	if len(info.ListSegments.Items) > 0 &&
-		(info.ListSegments.Items[0].PlainSize == 0 && info.ListSegments.Items[0].PlainOffset == 0) {
-		return packageError.New("old style objects not supported yet")
+	if _, compressed, _ := compression.InfoFromMetadata(info.Object.Metadata); compressed {
+		return packageError.New("compressed objects not supported yet")
+	}
+
+	if opts.Offset >= info.Object.Size {
+		return packageError.New("offset %d beyond the object size %d", opts.Offset, info.Object.Size)
 	}
 
 	if err := writer.Truncate(info.Object.Size); err != nil {
 		return packageError.Wrap(err)
 	}
 
-	// TODO combine it somehow with this same code from streams.Get()
-	// download all missing segments
-	for info.ListSegments.More {
-		var cursor storj.SegmentPosition
-		if len(info.ListSegments.Items) > 0 {
-			last := info.ListSegments.Items[len(info.ListSegments.Items)-1]
-			cursor = last.Position
-		}
-
-		result, err := db.ListSegments(ctx, metaclient.ListSegmentsParams{
-			StreamID: info.Object.ID,
-			Cursor:   cursor,
-			Range:    info.Range,
-		})
-		if err != nil {
-			return convertKnownErrors(err, bucket, key)
-		}
-
-		info.ListSegments.Items = append(info.ListSegments.Items, result.Items...)
-		info.ListSegments.More = result.More
+	streams, err := getStreamsStoreWithProject(ctx, project)
+	if err != nil {
+		return convertKnownErrors(err, bucket, key)
 	}
+	defer func() { err = errs.Combine(err, streams.Close()) }()
 
-	streams, err := getStreamsStoreWithProject(ctx, project)
+	// download all missing segments and calculate the plain offsets of the
+	// segments of old style objects from their fixed segment size.
+	info, err = streams.CompleteSegments(ctx, info)
 	if err != nil {
 		return convertKnownErrors(err, bucket, key)
 	}
@@ -177,18 +193,25 @@ func DownloadObjectAt(ctx context.Context, project *uplink.Project, bucket, key
 
 	limiter := sync2.NewLimiter(opts.Concurrency)
 	for _, segment := range info.ListSegments.Items {
-		segment := segment
+		// download only the part of the segment, which overlaps the range.
+		offset, limit := segment.PlainOffset, segment.PlainOffset+segment.PlainSize
+		if offset < info.Range.Start {
+			offset = info.Range.Start
+		}
+		if limit > info.Range.Limit {
+			limit = info.Range.Limit
+		}
 
 		// ignore empty segments because when object size is
 		// multplication of max segment then it will have
 		// empty inline segment at the end, not true for
 		// multipart upload.
-		if segment.PlainSize == 0 {
+		if offset >= limit {
 			continue
 		}
 
 		ok := limiter.Go(cancelCtx, func() {
-			downloadSegment(cancelCtx, ranger, segment, writer, addError)
+			downloadRange(cancelCtx, ranger, offset, limit-offset, writer, addError)
 		})
 		if !ok {
 			break
@@ -201,8 +224,8 @@ func DownloadObjectAt(ctx context.Context, project *uplink.Project, bucket, key
 	return convertKnownErrors(errGroup.Err(), bucket, key)
 }
 
-func downloadSegment(ctx context.Context, ranger ranger.Ranger, segment metaclient.SegmentListItem, writer io.WriterAt, addError func(err error)) {
-	reader, err := ranger.Range(ctx, segment.PlainOffset, segment.PlainSize)
+func downloadRange(ctx context.Context, ranger ranger.Ranger, offset, length int64, writer io.WriterAt, addError func(err error)) {
+	reader, err := ranger.Range(ctx, offset, length)
 	if err != nil {
 		addError(err)
 		return
@@ -214,7 +237,6 @@ func downloadSegment(ctx context.Context, ranger ranger.Ranger, segment metaclie
 		}
 	}()
 
-	offset := segment.PlainOffset
 	buffer := make([]byte, 32*memory.KiB.Int())
 	for {
 		if err := ctx.Err(); err != nil {
@@ -239,6 +261,67 @@ func downloadSegment(ctx context.Context, ranger ranger.Ranger, segment metaclie
 	}
 }
 
+// UploadObjectFromOptions options for UploadObjectFrom.
+type UploadObjectFromOptions struct {
+	// Concurrency is the number of segments which are uploaded in parallel.
+	// It defaults to 2.
+	Concurrency int
+	// When Expires is zero, there is no expiration.
+	Expires time.Time
+	// CustomMetadata is stored with the object, when not nil.
+	CustomMetadata uplink.CustomMetadata
+}
+
+// UploadObjectFrom uploads size bytes from the specified io.ReaderAt into an object.
+//
+// The data is split at the segment boundaries and the segments are read with
+// ReadAt by their concurrent uploads, so they aren't buffered in memory. The
+// checksums of the data are calculated by reading it once more, while the
+// segments are uploaded.
+func UploadObjectFrom(ctx context.Context, project *uplink.Project, bucket, key string, reader io.ReaderAt, size int64, opts *UploadObjectFromOptions) (_ *uplink.Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if project == nil {
+		return nil, packageError.New("project is nil")
+	}
+	if reader == nil {
+		return nil, packageError.New("reader is nil")
+	}
+	if size < 0 {
+		return nil, packageError.New("invalid size %d", size)
+	}
+
+	var options UploadObjectFromOptions
+	if opts != nil {
+		options = *opts
+	}
+	if options.Concurrency <= 0 {
+		options.Concurrency = 2
+	}
+
+	source := io.NewSectionReader(exactReaderAt{reader: reader, size: size}, 0, size)
+	return uploadObjectFromWithProject(ctx, project, bucket, key, source, options.CustomMetadata, &uplink.UploadOptions{
+		Expires:     options.Expires,
+		Concurrency: options.Concurrency,
+	})
+}
+
+// exactReaderAt fails with io.ErrUnexpectedEOF, when the reader ends before
+// size.
+type exactReaderAt struct {
+	reader io.ReaderAt
+	size   int64
+}
+
+// ReadAt implements io.ReaderAt.
+func (reader exactReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	n, err = reader.reader.ReadAt(p, off)
+	if errors.Is(err, io.EOF) && off+int64(n) < reader.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
 //go:linkname convertKnownErrors storj.io/uplink.convertKnownErrors
 func convertKnownErrors(err error, bucket, key string) error
 
@@ -247,3 +330,6 @@ func dialMetainfoDBWithProject(ctx context.Context, project *uplink.Project) (_
 
 //go:linkname getStreamsStoreWithProject storj.io/uplink.getStreamsStoreWithProject
 func getStreamsStoreWithProject(ctx context.Context, project *uplink.Project) (_ *streams.Store, err error)
+
+//go:linkname uploadObjectFromWithProject storj.io/uplink.uploadObjectFromWithProject
+func uploadObjectFromWithProject(ctx context.Context, project *uplink.Project, bucket, key string, source *io.SectionReader, custom uplink.CustomMetadata, options *uplink.UploadOptions) (_ *uplink.Object, err error)
@@ -15,6 +15,7 @@ import (
 	"storj.io/common/pb"
 	"storj.io/common/rpc"
 	"storj.io/common/storj"
+	"storj.io/uplink/private/ratelimit"
 )
 
 // Error is the default error class for piecestore client.
@@ -26,6 +27,11 @@ type Config struct {
 
 	InitialStep int64
 	MaximumStep int64
+
+	// UploadRate and DownloadRate limit the bandwidth of uploads and
+	// downloads, when they are not nil.
+	UploadRate   *ratelimit.Limiter
+	DownloadRate *ratelimit.Limiter
 }
 
 // DefaultConfig are the default params used for upload and download.
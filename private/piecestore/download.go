@@ -148,6 +148,14 @@ func (client *Download) Read(data []byte) (read int, err error) {
 
 			// send an order
 			if newAllocation > 0 {
+				// the storage node sends the data as soon as it receives the order,
+				// so wait until it can be received without exceeding the bandwidth limit
+				if err := client.client.config.DownloadRate.WaitN(ctx, newAllocation); err != nil {
+					client.unread.IncludeError(err)
+					client.closeWithError(err)
+					return read, err
+				}
+
 				order, err := signing.SignUplinkOrder(ctx, client.privateKey, &pb.Order{
 					SerialNumber: client.limit.SerialNumber,
 					Amount:       client.allocated + newAllocation,
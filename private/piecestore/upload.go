@@ -17,6 +17,7 @@ import (
 	"storj.io/common/pkcrypto"
 	"storj.io/common/signing"
 	"storj.io/common/storj"
+	"storj.io/uplink/private/progress"
 )
 
 var mon = monkit.Package()
@@ -120,6 +121,11 @@ func (client *upload) write(ctx context.Context, data io.Reader) (hash *pb.Piece
 		}
 		sendData = sendData[:n]
 
+		// wait until the data can be sent without exceeding the bandwidth limit
+		if err := client.client.config.UploadRate.WaitN(ctx, int64(len(sendData))); err != nil {
+			return nil, err
+		}
+
 		// create a signed order for the next chunk
 		order, err := signing.SignUplinkOrder(ctx, client.privateKey, &pb.Order{
 			SerialNumber: client.limit.SerialNumber,
@@ -151,6 +157,7 @@ func (client *upload) write(ctx context.Context, data io.Reader) (hash *pb.Piece
 
 		// update our offset
 		client.offset += int64(len(sendData))
+		progress.TrackerFromContext(ctx).AddNodeBytes(client.limit.StorageNodeId, int64(len(sendData)))
 
 		// update allocation step, incrementally building trust
 		client.allocationStep = client.client.nextAllocationStep(client.allocationStep)
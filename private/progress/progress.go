@@ -0,0 +1,173 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package progress implements tracking the progress of transfers.
+package progress
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"storj.io/common/storj"
+)
+
+// Snapshot is the progress of a transfer at some point in time.
+type Snapshot struct {
+	PlainBytes        int64
+	NodeBytes         map[storj.NodeID]int64
+	SegmentsCommitted int64
+	PiecesUploaded    int64
+	PiecesFailed      int64
+	PiecesCanceled    int64
+}
+
+// Tracker collects the progress of a transfer.
+//
+// The methods are safe for concurrent use and do nothing on a nil Tracker.
+type Tracker struct {
+	mu                sync.Mutex
+	plainBytes        int64
+	nodeBytes         map[storj.NodeID]int64
+	segmentsCommitted int64
+	piecesUploaded    int64
+	piecesFailed      int64
+	piecesCanceled    int64
+}
+
+// NewTracker returns a new tracker.
+func NewTracker() *Tracker {
+	return &Tracker{
+		nodeBytes: map[storj.NodeID]int64{},
+	}
+}
+
+// AddPlainBytes adds n plain bytes.
+func (tracker *Tracker) AddPlainBytes(n int64) {
+	if tracker == nil {
+		return
+	}
+	tracker.mu.Lock()
+	tracker.plainBytes += n
+	tracker.mu.Unlock()
+}
+
+// AddNodeBytes adds n encrypted bytes transferred to or from the node.
+func (tracker *Tracker) AddNodeBytes(node storj.NodeID, n int64) {
+	if tracker == nil {
+		return
+	}
+	tracker.mu.Lock()
+	tracker.nodeBytes[node] += n
+	tracker.mu.Unlock()
+}
+
+// AddSegmentsCommitted adds n committed segments.
+func (tracker *Tracker) AddSegmentsCommitted(n int64) {
+	if tracker == nil {
+		return
+	}
+	tracker.mu.Lock()
+	tracker.segmentsCommitted += n
+	tracker.mu.Unlock()
+}
+
+// AddPieces adds the numbers of uploaded, failed and canceled piece uploads
+// of a segment.
+func (tracker *Tracker) AddPieces(uploaded, failed, canceled int64) {
+	if tracker == nil {
+		return
+	}
+	tracker.mu.Lock()
+	tracker.piecesUploaded += uploaded
+	tracker.piecesFailed += failed
+	tracker.piecesCanceled += canceled
+	tracker.mu.Unlock()
+}
+
+// Snapshot returns the current progress.
+func (tracker *Tracker) Snapshot() Snapshot {
+	if tracker == nil {
+		return Snapshot{}
+	}
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	nodeBytes := make(map[storj.NodeID]int64, len(tracker.nodeBytes))
+	for node, n := range tracker.nodeBytes {
+		nodeBytes[node] = n
+	}
+
+	return Snapshot{
+		PlainBytes:        tracker.plainBytes,
+		NodeBytes:         nodeBytes,
+		SegmentsCommitted: tracker.segmentsCommitted,
+		PiecesUploaded:    tracker.piecesUploaded,
+		PiecesFailed:      tracker.piecesFailed,
+		PiecesCanceled:    tracker.piecesCanceled,
+	}
+}
+
+type trackerKey struct{}
+
+// WithTracker returns a context, which collects the progress of the transfers
+// done with it in tracker.
+func WithTracker(ctx context.Context, tracker *Tracker) context.Context {
+	return context.WithValue(ctx, trackerKey{}, tracker)
+}
+
+// TrackerFromContext returns the tracker of the context or nil.
+func TrackerFromContext(ctx context.Context) *Tracker {
+	tracker, _ := ctx.Value(trackerKey{}).(*Tracker)
+	return tracker
+}
+
+// Reporter calls a function with the progress of a tracker in regular intervals.
+type Reporter struct {
+	tracker *Tracker
+	report  func(Snapshot)
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// NewReporter starts reporting the progress of tracker to report every interval.
+func NewReporter(tracker *Tracker, interval time.Duration, report func(Snapshot)) *Reporter {
+	reporter := &Reporter{
+		tracker: tracker,
+		report:  report,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(reporter.stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				reporter.report(reporter.tracker.Snapshot())
+			case <-reporter.done:
+				return
+			}
+		}
+	}()
+
+	return reporter
+}
+
+// Stop stops the reporting and reports the final progress. It does nothing
+// on a nil Reporter.
+func (reporter *Reporter) Stop() {
+	if reporter == nil {
+		return
+	}
+	reporter.once.Do(func() {
+		close(reporter.done)
+		<-reporter.stopped
+		reporter.report(reporter.tracker.Snapshot())
+	})
+}
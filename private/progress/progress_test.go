@@ -0,0 +1,67 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package progress_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/testrand"
+	"storj.io/uplink/private/progress"
+)
+
+func TestTracker(t *testing.T) {
+	var nilTracker *progress.Tracker
+	nilTracker.AddPlainBytes(1)
+	require.Equal(t, progress.Snapshot{}, nilTracker.Snapshot())
+
+	tracker := progress.NewTracker()
+	ctx := progress.WithTracker(context.Background(), tracker)
+	require.Equal(t, tracker, progress.TrackerFromContext(ctx))
+	require.Nil(t, progress.TrackerFromContext(context.Background()))
+
+	node := testrand.NodeID()
+	tracker.AddPlainBytes(10)
+	tracker.AddNodeBytes(node, 5)
+	tracker.AddNodeBytes(node, 6)
+	tracker.AddSegmentsCommitted(1)
+	tracker.AddPieces(3, 1, 0)
+	tracker.AddPieces(2, 0, 2)
+
+	snapshot := tracker.Snapshot()
+	require.EqualValues(t, 10, snapshot.PlainBytes)
+	require.EqualValues(t, 11, snapshot.NodeBytes[node])
+	require.EqualValues(t, 1, snapshot.SegmentsCommitted)
+	require.EqualValues(t, 5, snapshot.PiecesUploaded)
+	require.EqualValues(t, 1, snapshot.PiecesFailed)
+	require.EqualValues(t, 2, snapshot.PiecesCanceled)
+}
+
+func TestReporter(t *testing.T) {
+	tracker := progress.NewTracker()
+
+	var mu sync.Mutex
+	var reports []progress.Snapshot
+	reporter := progress.NewReporter(tracker, time.Millisecond, func(snapshot progress.Snapshot) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports = append(reports, snapshot)
+	})
+
+	tracker.AddPlainBytes(10)
+	time.Sleep(20 * time.Millisecond)
+	tracker.AddPlainBytes(10)
+
+	reporter.Stop()
+	reporter.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.True(t, len(reports) >= 2)
+	require.EqualValues(t, 20, reports[len(reports)-1].PlainBytes)
+}
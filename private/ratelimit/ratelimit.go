@@ -0,0 +1,114 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package ratelimit implements limiting the bandwidth of transfers.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter limits the rate of transferred bytes with a token bucket.
+//
+// The methods are safe for concurrent use and a nil Limiter doesn't limit
+// anything.
+type Limiter struct {
+	rate  float64
+	burst int64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a limiter, which allows bytesPerSecond bytes per second
+// on average, with bursts of up to burst bytes. When burst is not positive, it
+// defaults to bytesPerSecond. It returns nil when bytesPerSecond isn't
+// positive.
+func NewLimiter(bytesPerSecond, burst int64) *Limiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = bytesPerSecond
+	}
+	return &Limiter{
+		rate:   float64(bytesPerSecond),
+		burst:  burst,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// WaitN waits until n bytes can be transferred.
+func (limiter *Limiter) WaitN(ctx context.Context, n int64) error {
+	if limiter == nil {
+		return nil
+	}
+
+	for n > 0 {
+		chunk := n
+		if chunk > limiter.burst {
+			chunk = limiter.burst
+		}
+		if err := limiter.wait(ctx, chunk); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// wait reserves n tokens, which must not be more than the burst, and waits
+// until they are available.
+func (limiter *Limiter) wait(ctx context.Context, n int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	limiter.mu.Lock()
+	now := time.Now()
+	limiter.tokens += now.Sub(limiter.last).Seconds() * limiter.rate
+	if limiter.tokens > float64(limiter.burst) {
+		limiter.tokens = float64(limiter.burst)
+	}
+	limiter.last = now
+
+	limiter.tokens -= float64(n)
+	delay := time.Duration(-limiter.tokens / limiter.rate * float64(time.Second))
+	limiter.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// return the reserved tokens, so that other transfers can use them
+		limiter.mu.Lock()
+		limiter.tokens += float64(n)
+		limiter.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+type limiterKey struct{}
+
+// WithLimiter returns a context, which overrides the limiter used for the
+// transfers done with it. A nil limiter disables limiting.
+func WithLimiter(ctx context.Context, limiter *Limiter) context.Context {
+	return context.WithValue(ctx, limiterKey{}, limiter)
+}
+
+// FromContext returns the limiter set with WithLimiter.
+func FromContext(ctx context.Context) (_ *Limiter, ok bool) {
+	limiter, ok := ctx.Value(limiterKey{}).(*Limiter)
+	return limiter, ok
+}
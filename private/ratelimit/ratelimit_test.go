@@ -0,0 +1,64 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/uplink/private/ratelimit"
+)
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	require.Nil(t, ratelimit.NewLimiter(0, 100))
+
+	var unlimited *ratelimit.Limiter
+	require.NoError(t, unlimited.WaitN(ctx, 1<<30))
+
+	limiter := ratelimit.NewLimiter(10000, 1000)
+
+	// the burst is available immediately
+	start := time.Now()
+	require.NoError(t, limiter.WaitN(ctx, 1000))
+	require.Less(t, time.Since(start), 50*time.Millisecond)
+
+	// the rest has to wait for the tokens
+	start = time.Now()
+	require.NoError(t, limiter.WaitN(ctx, 2000))
+	require.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestLimiterCancel(t *testing.T) {
+	limiter := ratelimit.NewLimiter(1, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, limiter.WaitN(ctx, 10), context.Canceled)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.NoError(t, limiter.WaitN(ctx, 1))
+	require.ErrorIs(t, limiter.WaitN(ctx, 1), context.DeadlineExceeded)
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+
+	_, ok := ratelimit.FromContext(ctx)
+	require.False(t, ok)
+
+	limiter := ratelimit.NewLimiter(100, 0)
+	override, ok := ratelimit.FromContext(ratelimit.WithLimiter(ctx, limiter))
+	require.True(t, ok)
+	require.Equal(t, limiter, override)
+
+	override, ok = ratelimit.FromContext(ratelimit.WithLimiter(ctx, nil))
+	require.True(t, ok)
+	require.Nil(t, override)
+}
@@ -0,0 +1,396 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+// Package segmentcache implements a size-bounded cache of the decrypted data
+// of segments on the local disk.
+package segmentcache
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spacemonkeygo/monkit/v3"
+	"github.com/zeebo/errs"
+
+	"storj.io/common/ranger"
+	"storj.io/common/readcloser"
+	"storj.io/common/storj"
+)
+
+var mon = monkit.Package()
+
+// Error is the default error class for the segment cache.
+var Error = errs.Class("segmentcache")
+
+// tempPrefix is the prefix of the files, which are being filled.
+const tempPrefix = ".tmp-"
+
+// Key identifies a segment of an object.
+type Key struct {
+	StreamID storj.StreamID
+	Position storj.SegmentPosition
+}
+
+// hash returns the name of the segment in the cache.
+func (key Key) hash() string {
+	var position [8]byte
+	binary.BigEndian.PutUint32(position[:4], uint32(key.Position.PartNumber))
+	binary.BigEndian.PutUint32(position[4:], uint32(key.Position.Index))
+
+	h := sha256.New()
+	_, _ = h.Write(key.StreamID)
+	_, _ = h.Write(position[:])
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// entry is a segment stored in the cache.
+type entry struct {
+	hash     string
+	modified int64 // modification time of the object in Unix nanoseconds
+	size     int64
+}
+
+// fileName returns the name of the file of the entry. It contains the
+// modification time, so that the entries can be validated after the cache
+// is opened again.
+func (e *entry) fileName() string {
+	return e.hash + "-" + strconv.FormatInt(e.modified, 10)
+}
+
+// Cache stores the decrypted data of segments in files of a directory. When
+// the total size of the stored segments exceeds the maximum size, the least
+// recently used segments are removed.
+//
+// The segments of an object are identified by its stream ID and validated
+// with its modification time, so they aren't used after the object has been
+// replaced.
+type Cache struct {
+	dir     string
+	maxSize int64
+
+	// refs is the number of times the cache has been opened and not closed.
+	// It's protected by openedMu.
+	refs int
+
+	mu      sync.Mutex
+	size    int64
+	entries map[string]*list.Element
+	lru     *list.List // of *entry, the most recently used first
+}
+
+// opened contains the caches opened in this process by their directory.
+// The caches of the same directory are shared, because they would remove the
+// files of each other otherwise.
+var (
+	openedMu sync.Mutex
+	opened   = map[string]*Cache{}
+)
+
+// Open opens the cache in the directory dir, which is created when it
+// doesn't exist. The segments already stored in the directory are used.
+//
+// A cache, which is already open in this process, is shared and has to be
+// opened with the same maximum size. Every opened cache must be closed.
+func Open(dir string, maxSize int64) (_ *Cache, err error) {
+	if dir == "" {
+		return nil, Error.New("directory is not set")
+	}
+	if maxSize <= 0 {
+		return nil, Error.New("max size must be positive, got %d", maxSize)
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	openedMu.Lock()
+	defer openedMu.Unlock()
+
+	if cache, ok := opened[dir]; ok {
+		if cache.maxSize != maxSize {
+			return nil, Error.New("cache %q is already open with max size %d", dir, cache.maxSize)
+		}
+		cache.refs++
+		return cache, nil
+	}
+
+	cache, err := load(dir, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	cache.refs = 1
+	opened[dir] = cache
+	return cache, nil
+}
+
+// Close closes the cache. The stored segments are kept in the directory.
+func (cache *Cache) Close() {
+	openedMu.Lock()
+	defer openedMu.Unlock()
+
+	cache.refs--
+	if cache.refs == 0 {
+		delete(opened, cache.dir)
+	}
+}
+
+// load loads the cache from the directory dir.
+func load(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	cache := &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, Error.Wrap(err)
+	}
+	// the access time of the entries is kept in the modification time of the files.
+	sort.Slice(infos, func(i, k int) bool {
+		return infos[i].ModTime().After(infos[k].ModTime())
+	})
+
+	var removeErrs errs.Group
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		e, ok := parseFileName(info.Name())
+		if !ok || cache.entries[e.hash] != nil {
+			// remove unfinished and unknown files.
+			removeErrs.Add(os.Remove(filepath.Join(dir, info.Name())))
+			continue
+		}
+		e.size = info.Size()
+		cache.entries[e.hash] = cache.lru.PushBack(e)
+		cache.size += e.size
+	}
+	if err := removeErrs.Err(); err != nil {
+		return nil, Error.Wrap(err)
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if err := cache.evict(0); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// parseFileName parses the entry from the name of its file.
+func parseFileName(name string) (*entry, bool) {
+	if strings.HasPrefix(name, tempPrefix) {
+		return nil, false
+	}
+	parts := strings.SplitN(name, "-", 2)
+	if len(parts) != 2 || len(parts[0]) != 2*sha256.Size {
+		return nil, false
+	}
+	modified, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &entry{hash: parts[0], modified: modified}, true
+}
+
+// Size returns the total size of the cached segments.
+func (cache *Cache) Size() int64 {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.size
+}
+
+// Ranger returns a ranger of the segment identified by key, whose object was
+// modified at modified. The segment is served from the cache, when it's
+// stored there. Otherwise the whole segment is read from rr and stored,
+// before the requested range is served.
+func (cache *Cache) Ranger(key Key, modified time.Time, rr ranger.Ranger) ranger.Ranger {
+	return &cachedRanger{
+		cache:    cache,
+		hash:     key.hash(),
+		modified: modified.UnixNano(),
+		ranger:   rr,
+	}
+}
+
+// open opens the file of the entry with the hash, when it's stored for the
+// modification time. Stale entries are removed.
+func (cache *Cache) open(hash string, modified int64) (*os.File, bool, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	element, ok := cache.entries[hash]
+	if !ok {
+		return nil, false, nil
+	}
+
+	e := element.Value.(*entry)
+	if e.modified != modified {
+		return nil, false, cache.remove(element)
+	}
+
+	path := filepath.Join(cache.dir, e.fileName())
+	file, err := os.Open(path)
+	if err != nil {
+		// the file was removed by someone else, so it's a miss.
+		return nil, false, cache.remove(element)
+	}
+
+	cache.lru.MoveToFront(element)
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	return file, true, nil
+}
+
+// add adds the filled temporary file as the entry.
+func (cache *Cache) add(tempPath string, e *entry) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if element, ok := cache.entries[e.hash]; ok {
+		if err := cache.remove(element); err != nil {
+			return errs.Combine(err, os.Remove(tempPath))
+		}
+	}
+	if err := cache.evict(e.size); err != nil {
+		return errs.Combine(err, os.Remove(tempPath))
+	}
+
+	if err := os.Rename(tempPath, filepath.Join(cache.dir, e.fileName())); err != nil {
+		return Error.Wrap(errs.Combine(err, os.Remove(tempPath)))
+	}
+	cache.entries[e.hash] = cache.lru.PushFront(e)
+	cache.size += e.size
+	return nil
+}
+
+// evict removes the least recently used entries, until there's space for
+// size more bytes. It must be called with mu held.
+func (cache *Cache) evict(size int64) error {
+	for cache.size+size > cache.maxSize && cache.lru.Len() > 0 {
+		if err := cache.remove(cache.lru.Back()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remove removes the entry of the element. It must be called with mu held.
+func (cache *Cache) remove(element *list.Element) error {
+	e := element.Value.(*entry)
+	cache.lru.Remove(element)
+	delete(cache.entries, e.hash)
+	cache.size -= e.size
+
+	err := os.Remove(filepath.Join(cache.dir, e.fileName()))
+	if err != nil && !os.IsNotExist(err) {
+		return Error.Wrap(err)
+	}
+	return nil
+}
+
+// cachedRanger serves a segment from the cache.
+type cachedRanger struct {
+	cache    *Cache
+	hash     string
+	modified int64
+	ranger   ranger.Ranger
+}
+
+// Size implements Ranger.Size.
+func (rr *cachedRanger) Size() int64 {
+	return rr.ranger.Size()
+}
+
+// Range implements Ranger.Range.
+func (rr *cachedRanger) Range(ctx context.Context, offset, length int64) (_ io.ReadCloser, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if offset < 0 {
+		return nil, Error.New("negative offset")
+	}
+	if length < 0 {
+		return nil, Error.New("negative length")
+	}
+	if offset+length > rr.Size() {
+		return nil, Error.New("range beyond end")
+	}
+
+	file, ok, err := rr.cache.open(rr.hash, rr.modified)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if rr.Size() > rr.cache.maxSize {
+			// the segment doesn't fit into the cache.
+			return rr.ranger.Range(ctx, offset, length)
+		}
+		if err := rr.fill(ctx); err != nil {
+			return nil, err
+		}
+		file, ok, err = rr.cache.open(rr.hash, rr.modified)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// the segment was evicted by the others in the meantime.
+			return rr.ranger.Range(ctx, offset, length)
+		}
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, Error.Wrap(errs.Combine(err, file.Close()))
+	}
+	return readcloser.LimitReadCloser(file, length), nil
+}
+
+// fill reads the whole segment and adds it to the cache.
+func (rr *cachedRanger) fill(ctx context.Context) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	reader, err := rr.ranger.Range(ctx, 0, rr.Size())
+	if err != nil {
+		return err
+	}
+	defer func() { err = errs.Combine(err, reader.Close()) }()
+
+	file, err := ioutil.TempFile(rr.cache.dir, tempPrefix)
+	if err != nil {
+		return Error.Wrap(err)
+	}
+	tempPath := file.Name()
+
+	n, err := io.Copy(file, reader)
+	err = errs.Combine(err, file.Close())
+	if err == nil && n != rr.Size() {
+		err = Error.New("segment size %d doesn't match the expected size %d", n, rr.Size())
+	}
+	if err != nil {
+		return errs.Combine(err, os.Remove(tempPath))
+	}
+
+	return rr.cache.add(tempPath, &entry{
+		hash:     rr.hash,
+		modified: rr.modified,
+		size:     n,
+	})
+}
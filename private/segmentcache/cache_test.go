@@ -0,0 +1,133 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package segmentcache_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/ranger"
+	"storj.io/common/storj"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/uplink/private/segmentcache"
+)
+
+// countingRanger counts the ranges read from it.
+type countingRanger struct {
+	ranger.Ranger
+
+	mu    sync.Mutex
+	reads int
+}
+
+func (rr *countingRanger) Range(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	rr.mu.Lock()
+	rr.reads++
+	rr.mu.Unlock()
+	return rr.Ranger.Range(ctx, offset, length)
+}
+
+func (rr *countingRanger) Reads() int {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	return rr.reads
+}
+
+func readRange(ctx context.Context, t *testing.T, rr ranger.Ranger, offset, length int64) []byte {
+	reader, err := rr.Range(ctx, offset, length)
+	require.NoError(t, err)
+	data, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+	return data
+}
+
+func TestCache(t *testing.T) {
+	ctx := testcontext.New(t)
+	dir := ctx.Dir("cache")
+
+	_, err := segmentcache.Open(dir, 0)
+	require.Error(t, err)
+
+	cache, err := segmentcache.Open(dir, 250)
+	require.NoError(t, err)
+
+	streamID := storj.StreamID(testrand.BytesInt(32))
+	modified := time.Now()
+
+	segments := make([][]byte, 3)
+	keys := make([]segmentcache.Key, 3)
+	for i := range segments {
+		segments[i] = testrand.BytesInt(100)
+		keys[i] = segmentcache.Key{StreamID: streamID, Position: storj.SegmentPosition{Index: int32(i)}}
+	}
+
+	// the first read fills the cache with the whole segment
+	source := &countingRanger{Ranger: ranger.ByteRanger(segments[0])}
+	rr := cache.Ranger(keys[0], modified, source)
+	require.Equal(t, segments[0][10:20], readRange(ctx, t, rr, 10, 10))
+	require.Equal(t, segments[0], readRange(ctx, t, rr, 0, 100))
+	require.Equal(t, 1, source.Reads())
+	require.EqualValues(t, 100, cache.Size())
+
+	// a changed modification time invalidates the segment
+	changed := &countingRanger{Ranger: ranger.ByteRanger(segments[0])}
+	require.Equal(t, segments[0], readRange(ctx, t, cache.Ranger(keys[0], modified.Add(time.Second), changed), 0, 100))
+	require.Equal(t, 1, changed.Reads())
+	require.EqualValues(t, 100, cache.Size())
+
+	_, err = cache.Ranger(keys[0], modified, source).Range(ctx, 50, 51)
+	require.Error(t, err)
+
+	// the least recently used segment is evicted
+	modified = modified.Add(time.Second)
+	readRange(ctx, t, cache.Ranger(keys[1], modified, ranger.ByteRanger(segments[1])), 0, 100)
+	readRange(ctx, t, cache.Ranger(keys[0], modified, ranger.ByteRanger(segments[0])), 0, 100)
+	readRange(ctx, t, cache.Ranger(keys[2], modified, ranger.ByteRanger(segments[2])), 0, 100)
+	require.EqualValues(t, 200, cache.Size())
+
+	evicted := &countingRanger{Ranger: ranger.ByteRanger(segments[1])}
+	readRange(ctx, t, cache.Ranger(keys[1], modified, evicted), 0, 100)
+	require.Equal(t, 1, evicted.Reads())
+
+	// segments larger than the cache aren't stored
+	large := testrand.BytesInt(300)
+	largeKey := segmentcache.Key{StreamID: streamID, Position: storj.SegmentPosition{Index: 3}}
+	require.Equal(t, large, readRange(ctx, t, cache.Ranger(largeKey, modified, ranger.ByteRanger(large)), 0, 300))
+	require.EqualValues(t, 200, cache.Size())
+
+	// the cache is shared, while it's open
+	shared, err := segmentcache.Open(dir, 250)
+	require.NoError(t, err)
+	require.Same(t, cache, shared)
+	shared.Close()
+
+	_, err = segmentcache.Open(dir, 150)
+	require.Error(t, err)
+
+	// the segments are kept, when the cache is opened again
+	cache.Close()
+	reopened, err := segmentcache.Open(dir, 250)
+	require.NoError(t, err)
+	require.NotSame(t, cache, reopened)
+	require.EqualValues(t, 200, reopened.Size())
+
+	cached := &countingRanger{Ranger: ranger.ByteRanger(segments[1])}
+	require.Equal(t, segments[1], readRange(ctx, t, reopened.Ranger(keys[1], modified, cached), 0, 100))
+	require.Zero(t, cached.Reads())
+	reopened.Close()
+
+	// opening with a smaller size evicts the segments
+	smaller, err := segmentcache.Open(dir, 150)
+	require.NoError(t, err)
+	require.EqualValues(t, 100, smaller.Size())
+	smaller.Close()
+}
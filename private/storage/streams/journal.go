@@ -0,0 +1,102 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package streams
+
+import (
+	"github.com/zeebo/errs"
+
+	"storj.io/common/storj"
+	"storj.io/uplink/private/metaclient"
+	"storj.io/uplink/private/progress"
+)
+
+// Journal records the progress of an upload, so that it can be resumed
+// after the process has been interrupted.
+type Journal interface {
+	// BeginObject is called when the satellite has started the object upload.
+	BeginObject(streamID storj.StreamID) error
+	// CommitSegment is called when the segment with the specified index has been
+	// committed. Segments are always reported in order.
+	CommitSegment(index int64, plainSize int64) error
+	// CommitObject is called when the object has been committed.
+	CommitObject() error
+}
+
+// ResumeState describes the already committed part of an interrupted upload.
+type ResumeState struct {
+	StreamID storj.StreamID
+	// SegmentSizes contains the plain sizes of the committed segments, in order.
+	SegmentSizes []int64
+}
+
+// Size returns the number of bytes that have been committed.
+func (state *ResumeState) Size() (size int64) {
+	for _, segmentSize := range state.SegmentSizes {
+		size += segmentSize
+	}
+	return size
+}
+
+// journalProgress reports the committed segments to the journal and to the
+// progress tracker.
+type journalProgress struct {
+	journal Journal
+	tracker *progress.Tracker
+	// sizes contains the plain sizes of the segments read so far.
+	sizes     []int64
+	committed int
+}
+
+// AddSegment records the plain size of the next segment.
+func (journaled *journalProgress) AddSegment(size int64) {
+	journaled.sizes = append(journaled.sizes, size)
+}
+
+// BeginObject reports that the object upload has started.
+func (journaled *journalProgress) BeginObject(streamID storj.StreamID) error {
+	if journaled.journal == nil {
+		return nil
+	}
+	return journaled.journal.BeginObject(streamID)
+}
+
+// Sent reports the segments committed by successfully sent requests.
+func (journaled *journalProgress) Sent(requests ...metaclient.BatchItem) error {
+	journaled.tracker.AddSegmentsCommitted(countSegmentCommits(requests))
+
+	if journaled.journal == nil {
+		return nil
+	}
+
+	for _, request := range requests {
+		switch request.(type) {
+		case *metaclient.CommitSegmentParams, *metaclient.MakeInlineSegmentParams:
+			if journaled.committed >= len(journaled.sizes) {
+				return errs.New("committing unknown segment %d", journaled.committed)
+			}
+			err := journaled.journal.CommitSegment(int64(journaled.committed), journaled.sizes[journaled.committed])
+			if err != nil {
+				return err
+			}
+			journaled.committed++
+		case *metaclient.CommitObjectParams:
+			if err := journaled.journal.CommitObject(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// countSegmentCommits returns the number of requests, which commit a segment.
+func countSegmentCommits(requests []metaclient.BatchItem) (count int64) {
+	for _, request := range requests {
+		switch request.(type) {
+		case *metaclient.CommitSegmentParams, *metaclient.MakeInlineSegmentParams:
+			count++
+		}
+	}
+	return count
+}
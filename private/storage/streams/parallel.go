@@ -0,0 +1,168 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package streams
+
+import (
+	"context"
+
+	"storj.io/uplink/private/metaclient"
+)
+
+// segmentUploads keeps track of remote segments which are uploaded to the
+// storage nodes in the background and returns their commit requests in the
+// order in which the segments were started.
+//
+// When the limit is less than 2, segments are uploaded synchronously.
+type segmentUploads struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	limit  int
+
+	pending   []*pendingSegment
+	collected []metaclient.BatchItem
+}
+
+// pendingSegment is a single segment upload started by segmentUploads.
+type pendingSegment struct {
+	done    chan struct{}
+	request metaclient.BatchItem
+	err     error
+}
+
+func newSegmentUploads(ctx context.Context, limit int) *segmentUploads {
+	ctx, cancel := context.WithCancel(ctx)
+	return &segmentUploads{
+		ctx:    ctx,
+		cancel: cancel,
+		limit:  limit,
+	}
+}
+
+// Concurrent returns true when segments are uploaded in the background, which
+// means that the segment data needs to be buffered before calling Go.
+func (uploads *segmentUploads) Concurrent() bool {
+	return uploads.limit > 1
+}
+
+// Go uploads a segment with fn. fn returns the request which commits the
+// segment.
+func (uploads *segmentUploads) Go(fn func(ctx context.Context) (metaclient.BatchItem, error)) error {
+	if !uploads.Concurrent() {
+		request, err := fn(uploads.ctx)
+		if err != nil {
+			return err
+		}
+		uploads.collected = append(uploads.collected, request)
+		return nil
+	}
+
+	segment := &pendingSegment{done: make(chan struct{})}
+	uploads.pending = append(uploads.pending, segment)
+
+	go func() {
+		defer close(segment.done)
+		segment.request, segment.err = fn(uploads.ctx)
+		if segment.err != nil {
+			uploads.cancel()
+		}
+	}()
+
+	return nil
+}
+
+// Add adds a request that doesn't require any upload, keeping the order with
+// the segments that are still being uploaded.
+func (uploads *segmentUploads) Add(request metaclient.BatchItem) {
+	if len(uploads.pending) == 0 {
+		uploads.collected = append(uploads.collected, request)
+		return
+	}
+
+	segment := &pendingSegment{
+		done:    make(chan struct{}),
+		request: request,
+	}
+	close(segment.done)
+	uploads.pending = append(uploads.pending, segment)
+}
+
+// Wait blocks until it's possible to start another segment upload.
+func (uploads *segmentUploads) Wait() error {
+	if uploads.limit <= 1 {
+		return uploads.waitPending(0)
+	}
+	return uploads.waitPending(uploads.limit - 1)
+}
+
+// Take returns the commit requests of the segments that have finished uploading,
+// in the segment order.
+func (uploads *segmentUploads) Take() ([]metaclient.BatchItem, error) {
+	for len(uploads.pending) > 0 {
+		select {
+		case <-uploads.pending[0].done:
+		default:
+			return uploads.take(), nil
+		}
+		if err := uploads.pop(); err != nil {
+			return nil, err
+		}
+	}
+	return uploads.take(), nil
+}
+
+// Finish waits for all the segments to be uploaded and returns the remaining
+// commit requests in the segment order.
+func (uploads *segmentUploads) Finish() ([]metaclient.BatchItem, error) {
+	if err := uploads.waitPending(0); err != nil {
+		return nil, err
+	}
+	return uploads.take(), nil
+}
+
+// Close cancels all the uploads which are still in progress and waits for them
+// to finish.
+func (uploads *segmentUploads) Close() {
+	uploads.cancel()
+	for _, segment := range uploads.pending {
+		<-segment.done
+	}
+	uploads.pending = nil
+}
+
+func (uploads *segmentUploads) waitPending(n int) error {
+	for len(uploads.pending) > n {
+		select {
+		case <-uploads.pending[0].done:
+		case <-uploads.ctx.Done():
+			// the failed segment may not be the first one
+			for _, segment := range uploads.pending {
+				<-segment.done
+				if segment.err != nil {
+					return segment.err
+				}
+			}
+			return uploads.ctx.Err()
+		}
+		if err := uploads.pop(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (uploads *segmentUploads) pop() error {
+	segment := uploads.pending[0]
+	if segment.err != nil {
+		return segment.err
+	}
+	uploads.pending = uploads.pending[1:]
+	uploads.collected = append(uploads.collected, segment.request)
+	return nil
+}
+
+func (uploads *segmentUploads) take() []metaclient.BatchItem {
+	collected := uploads.collected
+	uploads.collected = nil
+	return collected
+}
@@ -0,0 +1,87 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package streams
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/uplink/private/metaclient"
+)
+
+func TestSegmentUploadsOrder(t *testing.T) {
+	ctx := context.Background()
+
+	uploads := newSegmentUploads(ctx, 3)
+	defer uploads.Close()
+	require.True(t, uploads.Concurrent())
+
+	release := []chan struct{}{make(chan struct{}), make(chan struct{})}
+	for i := range release {
+		i := i
+		require.NoError(t, uploads.Wait())
+		require.NoError(t, uploads.Go(func(ctx context.Context) (metaclient.BatchItem, error) {
+			<-release[i]
+			return &metaclient.CommitSegmentParams{PlainSize: int64(i)}, nil
+		}))
+	}
+	uploads.Add(&metaclient.MakeInlineSegmentParams{PlainSize: 2})
+
+	// the second segment finishes first, but it cannot be committed before the first one
+	close(release[1])
+	time.Sleep(10 * time.Millisecond)
+	finished, err := uploads.Take()
+	require.NoError(t, err)
+	require.Empty(t, finished)
+
+	close(release[0])
+	finished, err = uploads.Finish()
+	require.NoError(t, err)
+	require.Len(t, finished, 3)
+	require.EqualValues(t, 0, finished[0].(*metaclient.CommitSegmentParams).PlainSize)
+	require.EqualValues(t, 1, finished[1].(*metaclient.CommitSegmentParams).PlainSize)
+	require.EqualValues(t, 2, finished[2].(*metaclient.MakeInlineSegmentParams).PlainSize)
+}
+
+func TestSegmentUploadsSequential(t *testing.T) {
+	ctx := context.Background()
+
+	uploads := newSegmentUploads(ctx, 0)
+	defer uploads.Close()
+	require.False(t, uploads.Concurrent())
+
+	called := false
+	require.NoError(t, uploads.Go(func(ctx context.Context) (metaclient.BatchItem, error) {
+		called = true
+		return &metaclient.CommitSegmentParams{}, nil
+	}))
+	require.True(t, called)
+
+	finished, err := uploads.Take()
+	require.NoError(t, err)
+	require.Len(t, finished, 1)
+}
+
+func TestSegmentUploadsError(t *testing.T) {
+	ctx := context.Background()
+
+	uploads := newSegmentUploads(ctx, 2)
+	defer uploads.Close()
+
+	failure := errors.New("failure")
+	require.NoError(t, uploads.Go(func(ctx context.Context) (metaclient.BatchItem, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	require.NoError(t, uploads.Go(func(ctx context.Context) (metaclient.BatchItem, error) {
+		return nil, failure
+	}))
+
+	_, err := uploads.Finish()
+	require.Error(t, err)
+}
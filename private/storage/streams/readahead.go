@@ -0,0 +1,221 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package streams
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/common/ranger"
+	"storj.io/uplink/private/membudget"
+)
+
+// readAheadRanger concatenates the rangers of segments. Its readers start
+// reading the following segments in the background.
+type readAheadRanger struct {
+	segments  []ranger.Ranger
+	size      int64
+	readAhead int
+}
+
+func newReadAheadRanger(segments []ranger.Ranger, readAhead int) *readAheadRanger {
+	rr := &readAheadRanger{
+		segments:  segments,
+		readAhead: readAhead,
+	}
+	for _, segment := range segments {
+		rr.size += segment.Size()
+	}
+	return rr
+}
+
+// Size implements Ranger.Size.
+func (rr *readAheadRanger) Size() int64 {
+	return rr.size
+}
+
+// Range implements Ranger.Range.
+func (rr *readAheadRanger) Range(ctx context.Context, offset, length int64) (_ io.ReadCloser, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if offset < 0 {
+		return nil, errs.New("negative offset")
+	}
+	if length < 0 {
+		return nil, errs.New("negative length")
+	}
+	if offset+length > rr.size {
+		return nil, errs.New("range beyond end")
+	}
+
+	var parts []segmentPart
+	for _, segment := range rr.segments {
+		if length == 0 {
+			break
+		}
+
+		size := segment.Size()
+		if offset >= size {
+			offset -= size
+			continue
+		}
+
+		partLength := size - offset
+		if partLength > length {
+			partLength = length
+		}
+		parts = append(parts, segmentPart{ranger: segment, offset: offset, length: partLength})
+
+		offset = 0
+		length -= partLength
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	return &readAheadReader{
+		ctx:       ctx,
+		cancel:    cancel,
+		parts:     parts,
+		readAhead: rr.readAhead,
+	}, nil
+}
+
+// segmentPart is a range of a segment.
+type segmentPart struct {
+	ranger ranger.Ranger
+	offset int64
+	length int64
+}
+
+// errNotOpened is the error of a reader, which wasn't opened in advance,
+// because the previous one failed.
+var errNotOpened = errors.New("segment not opened in advance")
+
+// segmentReader is a reader of a segment, which is opened in the background.
+type segmentReader struct {
+	part      segmentPart
+	readAhead bool
+	done      chan struct{}
+	reader    io.ReadCloser
+	err       error
+}
+
+// readAheadReader reads the parts one after another, while the readers of the
+// following readAhead parts are opened in the background.
+type readAheadReader struct {
+	ctx       context.Context
+	cancel    func()
+	parts     []segmentPart
+	readAhead int
+
+	// pending contains the opened readers, starting with the current one.
+	pending []*segmentReader
+	err     error
+}
+
+// open starts opening readers until readAhead readers follow the current one.
+//
+// A reader is opened in advance only after the previous one has been opened,
+// so that it holds its memory reservation. The readers opened in advance don't
+// wait for the memory budget, because the memory may be held by the readers,
+// which have to be read first.
+func (reader *readAheadReader) open() {
+	for len(reader.parts) > 0 && len(reader.pending) <= reader.readAhead {
+		var previous *segmentReader
+		if len(reader.pending) > 0 {
+			previous = reader.pending[len(reader.pending)-1]
+		}
+
+		segment := &segmentReader{
+			part:      reader.parts[0],
+			readAhead: previous != nil,
+			done:      make(chan struct{}),
+		}
+		reader.parts = reader.parts[1:]
+
+		go func() {
+			defer close(segment.done)
+
+			ctx := reader.ctx
+			if previous != nil {
+				<-previous.done
+				if previous.err != nil {
+					segment.err = errNotOpened
+					return
+				}
+				ctx = membudget.WithFailFast(ctx)
+			}
+			segment.reader, segment.err = segment.part.ranger.Range(ctx, segment.part.offset, segment.part.length)
+		}()
+		reader.pending = append(reader.pending, segment)
+	}
+}
+
+// Read implements io.Reader.
+func (reader *readAheadReader) Read(p []byte) (n int, err error) {
+	if reader.err != nil {
+		return 0, reader.err
+	}
+
+	for {
+		reader.open()
+		if len(reader.pending) == 0 {
+			return 0, io.EOF
+		}
+
+		current := reader.pending[0]
+		<-current.done
+		if current.readAhead && (errors.Is(current.err, errNotOpened) || errors.Is(current.err, membudget.ErrExhausted)) {
+			// it couldn't be opened in advance, but now it's the reader to
+			// wait for.
+			current.readAhead = false
+			current.reader, current.err = current.part.ranger.Range(reader.ctx, current.part.offset, current.part.length)
+		}
+		if current.err != nil {
+			reader.err = current.err
+			return 0, current.err
+		}
+
+		n, err = current.reader.Read(p)
+		if !errors.Is(err, io.EOF) {
+			return n, err
+		}
+
+		reader.pending = reader.pending[1:]
+		if err := current.reader.Close(); err != nil {
+			reader.err = err
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// Close implements io.Closer. Errors of closing the readers, which were opened
+// only in advance, are ignored.
+func (reader *readAheadReader) Close() (err error) {
+	if len(reader.pending) > 0 {
+		current := reader.pending[0]
+		reader.pending = reader.pending[1:]
+
+		<-current.done
+		if current.reader != nil {
+			err = current.reader.Close()
+		}
+	}
+
+	reader.cancel()
+	for _, segment := range reader.pending {
+		<-segment.done
+		if segment.reader != nil {
+			_ = segment.reader.Close()
+		}
+	}
+	reader.pending = nil
+
+	return err
+}
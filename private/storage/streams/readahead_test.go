@@ -0,0 +1,144 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package streams
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/ranger"
+	"storj.io/common/testrand"
+	"storj.io/uplink/private/membudget"
+)
+
+// recordingRanger records which of the segments were opened.
+type recordingRanger struct {
+	ranger.Ranger
+	num    int
+	mu     *sync.Mutex
+	opened map[int]bool
+}
+
+func (rr *recordingRanger) Range(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	rr.mu.Lock()
+	rr.opened[rr.num] = true
+	rr.mu.Unlock()
+	return rr.Ranger.Range(ctx, offset, length)
+}
+
+func TestReadAheadRanger(t *testing.T) {
+	ctx := context.Background()
+
+	data := testrand.BytesInt(1000)
+
+	var mu sync.Mutex
+	opened := map[int]bool{}
+
+	var segments []ranger.Ranger
+	for i := 0; i < 10; i++ {
+		segments = append(segments, &recordingRanger{
+			Ranger: ranger.ByteRanger(data[i*100 : (i+1)*100]),
+			num:    i,
+			mu:     &mu,
+			opened: opened,
+		})
+	}
+
+	rr := newReadAheadRanger(segments, 2)
+	require.EqualValues(t, len(data), rr.Size())
+
+	for _, tc := range []struct{ offset, length int64 }{
+		{0, 1000}, {0, 0}, {50, 100}, {99, 2}, {100, 100}, {950, 50}, {333, 444},
+	} {
+		reader, err := rr.Range(ctx, tc.offset, tc.length)
+		require.NoError(t, err)
+		read, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		require.Equal(t, data[tc.offset:tc.offset+tc.length], read)
+	}
+
+	_, err := rr.Range(ctx, 900, 101)
+	require.Error(t, err)
+
+	// reading the first segment opens the two following ones
+	for num := range opened {
+		delete(opened, num)
+	}
+	reader, err := rr.Range(ctx, 0, 1000)
+	require.NoError(t, err)
+	_, err = io.ReadFull(reader, make([]byte, 10))
+	require.NoError(t, err)
+	require.NoError(t, reader.Close())
+
+	mu.Lock()
+	require.Equal(t, map[int]bool{0: true, 1: true, 2: true}, opened)
+	mu.Unlock()
+}
+
+// budgetRanger reserves memory of the budget while its readers are open.
+type budgetRanger struct {
+	ranger.Ranger
+	budget *membudget.Budget
+	memory int64
+}
+
+func (rr *budgetRanger) Range(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	release, err := rr.budget.Acquire(ctx, rr.memory)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := rr.Ranger.Range(ctx, offset, length)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &releaseReader{ReadCloser: reader, release: release}, nil
+}
+
+type releaseReader struct {
+	io.ReadCloser
+	release func()
+}
+
+func (reader *releaseReader) Close() error {
+	defer reader.release()
+	return reader.ReadCloser.Close()
+}
+
+func TestReadAheadRanger_MemoryBudget(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	data := testrand.BytesInt(1000)
+
+	// the budget is smaller than the memory of the segments read at once
+	budget := membudget.New(250, false)
+
+	var segments []ranger.Ranger
+	for i := 0; i < 10; i++ {
+		segments = append(segments, &budgetRanger{
+			Ranger: ranger.ByteRanger(data[i*100 : (i+1)*100]),
+			budget: budget,
+			memory: 100,
+		})
+	}
+
+	rr := newReadAheadRanger(segments, 3)
+	for i := 0; i < 10; i++ {
+		reader, err := rr.Range(ctx, 0, 1000)
+		require.NoError(t, err)
+		read, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		require.Equal(t, data, read)
+		require.Zero(t, budget.Used())
+	}
+}
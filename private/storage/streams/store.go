@@ -4,8 +4,10 @@
 package streams
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
+	"errors"
 	"io"
 	"io/ioutil"
 	mathrand "math/rand" // Using mathrand here because crypto-graphic randomness is not required.
@@ -22,9 +24,13 @@ import (
 	"storj.io/common/pb"
 	"storj.io/common/ranger"
 	"storj.io/common/storj"
+	"storj.io/uplink/private/corruption"
 	"storj.io/uplink/private/ecclient"
 	"storj.io/uplink/private/eestream"
+	"storj.io/uplink/private/membudget"
 	"storj.io/uplink/private/metaclient"
+	"storj.io/uplink/private/progress"
+	"storj.io/uplink/private/segmentcache"
 	"storj.io/uplink/private/testuplink"
 )
 
@@ -70,6 +76,8 @@ type Store struct {
 	encStore             *encryption.Store
 	encryptionParameters storj.EncryptionParameters
 	inlineThreshold      int
+	cache                *segmentcache.Cache
+	budget               *membudget.Budget
 
 	rngMu sync.Mutex
 	rng   *mathrand.Rand
@@ -95,6 +103,20 @@ func NewStreamStore(metainfo *metaclient.Client, ec ecclient.Client, segmentSize
 	}, nil
 }
 
+// WithCache makes the downloads serve the remote segments from cache and
+// store the downloaded ones there.
+func (s *Store) WithCache(cache *segmentcache.Cache) *Store {
+	s.cache = cache
+	return s
+}
+
+// WithMemoryBudget makes the concurrent uploads reserve the memory of the
+// buffered segments from budget.
+func (s *Store) WithMemoryBudget(budget *membudget.Budget) *Store {
+	s.budget = budget
+	return s
+}
+
 // Close closes the underlying resources passed to the metainfo DB.
 func (s *Store) Close() error {
 	return s.metainfo.Close()
@@ -107,6 +129,33 @@ func (s *Store) Close() error {
 //
 // If there is an error, it cleans up any uploaded segment before returning.
 func (s *Store) Put(ctx context.Context, bucket, unencryptedKey string, data io.Reader, metadata Metadata, expiration time.Time) (_ Meta, err error) {
+	return s.PutWithOptions(ctx, bucket, unencryptedKey, data, metadata, expiration, PutOptions{})
+}
+
+// PutOptions contains additional options for uploading a stream.
+type PutOptions struct {
+	// Concurrency is the number of segments which are encrypted and uploaded
+	// at the same time. Every segment which is being uploaded is buffered in
+	// memory, so the memory used is bounded by Concurrency times the segment size.
+	// When Concurrency is less than 2 the segments are uploaded one by one
+	// without buffering.
+	Concurrency int
+
+	// Journal, when not nil, records the progress of the upload. The object
+	// is not deleted when the upload fails, so that it can be resumed.
+	Journal Journal
+	// Resume continues an interrupted upload. The data must start right
+	// after the segments which have been already committed.
+	Resume *ResumeState
+
+	// Source, when not nil, is read instead of the data. The segments, which
+	// are uploaded concurrently, read their part of it with ReadAt, so they
+	// aren't buffered in memory.
+	Source *io.SectionReader
+}
+
+// PutWithOptions is like Put, but allows to specify additional options.
+func (s *Store) PutWithOptions(ctx context.Context, bucket, unencryptedKey string, data io.Reader, metadata Metadata, expiration time.Time, opts PutOptions) (_ Meta, err error) {
 	defer mon.Task()(&ctx)(&err)
 	derivedKey, err := encryption.DeriveContentKey(bucket, paths.NewUnencrypted(unencryptedKey), s.encStore)
 	if err != nil {
@@ -126,31 +175,77 @@ func (s *Store) Put(ctx context.Context, bucket, unencryptedKey string, data io.
 
 	var streamID storj.StreamID
 	defer func() {
-		if err != nil && !streamID.IsZero() {
+		// resumable uploads keep the pending object, so they can be continued later
+		if err != nil && !streamID.IsZero() && opts.Journal == nil {
 			s.deleteCancelledObject(context2.WithoutCancellation(ctx), bucket, encPath.Raw(), streamID)
 			return
 		}
 	}()
 
 	var (
-		currentSegment      int64
-		contentKey          storj.Key
-		streamSize          int64
-		lastSegmentSize     int64
-		encryptedKey        []byte
-		keyNonce            storj.Nonce
-		objectRS, segmentRS eestream.RedundancyStrategy
+		currentSegment  int64
+		contentKey      storj.Key
+		streamSize      int64
+		lastSegmentSize int64
+		encryptedKey    []byte
+		keyNonce        storj.Nonce
+		objectRS        eestream.RedundancyStrategy
 
 		requestsToBatch = make([]metaclient.BatchItem, 0, 2)
+
+		journaled = journalProgress{
+			journal: opts.Journal,
+			tracker: progress.TrackerFromContext(ctx),
+		}
 	)
 
+	if opts.Resume != nil {
+		if opts.Resume.StreamID.IsZero() {
+			return Meta{}, errs.New("resumed upload is missing stream ID")
+		}
+		streamID = opts.Resume.StreamID
+		for _, size := range opts.Resume.SegmentSizes {
+			journaled.AddSegment(size)
+			journaled.committed++
+			lastSegmentSize = size
+			streamSize += size
+			currentSegment++
+		}
+	}
+
 	maxEncryptedSegmentSize, err := encryption.CalcEncryptedSize(s.segmentSize, s.encryptionParameters)
 	if err != nil {
 		return Meta{}, err
 	}
 
+	uploads := newSegmentUploads(ctx, opts.Concurrency)
+	defer uploads.Close()
+
+	// releaseSegment releases the memory of the buffered segment, which hasn't
+	// been handed over to its upload.
+	var releaseSegment func()
+	defer func() {
+		if releaseSegment != nil {
+			releaseSegment()
+		}
+	}()
+
 	eofReader := NewEOFReader(data)
-	for !eofReader.IsEOF() && !eofReader.HasError() {
+	// more returns whether there is another segment and nextSegment returns
+	// the reader of its data.
+	more := func() bool { return !eofReader.IsEOF() && !eofReader.HasError() }
+	nextSegment := func() io.Reader { return io.LimitReader(eofReader, s.segmentSize) }
+	if opts.Source != nil {
+		sections := &sectionsReader{source: opts.Source, segmentSize: s.segmentSize}
+		more, nextSegment = sections.more, sections.next
+	}
+
+	for more() {
+		// wait until there is room for uploading another segment
+		if err := uploads.Wait(); err != nil {
+			return Meta{}, err
+		}
+
 		// generate random key for encrypting the segment's content
 		_, err := rand.Read(contentKey[:])
 		if err != nil {
@@ -179,9 +274,8 @@ func (s *Store) Put(ctx context.Context, bucket, unencryptedKey string, data io.
 			return Meta{}, err
 		}
 
-		sizeReader := SizeReader(eofReader)
-		segmentReader := io.LimitReader(sizeReader, s.segmentSize)
-		peekReader := NewPeekThresholdReader(segmentReader)
+		sizeReader := SizeReader(nextSegment())
+		peekReader := NewPeekThresholdReader(sizeReader)
 		// If the data is larger than the inline threshold size, then it will be a remote segment
 		isRemote, err := peekReader.IsLargerThan(s.inlineThreshold)
 		if err != nil {
@@ -197,12 +291,35 @@ func (s *Store) Put(ctx context.Context, bucket, unencryptedKey string, data io.
 		}
 
 		if isRemote {
-			encrypter, err := encryption.NewEncrypter(s.encryptionParameters.CipherSuite, &contentKey, &contentNonce, int(s.encryptionParameters.BlockSize))
+			// the sections of the source are read independently of each
+			// other, so they don't need to be buffered.
+			var segmentData io.Reader = peekReader
+			if uploads.Concurrent() && opts.Source == nil {
+				// the buffered segment and its encoded data are reserved
+				// together, so that the upload doesn't wait for memory held
+				// by the buffers of the other segments.
+				releaseSegment, err = s.budget.Acquire(ctx, s.segmentSize+maxEncryptedSegmentSize)
+				if err != nil {
+					return Meta{}, err
+				}
+
+				// the segment is uploaded in the background, so it needs to be
+				// read before the next segment can be read.
+				buffer, err := ioutil.ReadAll(peekReader)
+				if err != nil {
+					return Meta{}, err
+				}
+				segmentData = bytes.NewReader(buffer)
+			}
+
+			// contentKey is overwritten by the next segment, while this one may still be uploading.
+			segmentKey := contentKey
+			encrypter, err := encryption.NewEncrypter(s.encryptionParameters.CipherSuite, &segmentKey, &contentNonce, int(s.encryptionParameters.BlockSize))
 			if err != nil {
 				return Meta{}, err
 			}
 
-			paddedReader := encryption.PadReader(ioutil.NopCloser(peekReader), encrypter.InBlockSize())
+			paddedReader := encryption.PadReader(ioutil.NopCloser(segmentData), encrypter.InBlockSize())
 			transformedReader := encryption.TransformReader(paddedReader, encrypter, 0)
 
 			beginSegment := &metaclient.BeginSegmentParams{
@@ -213,7 +330,7 @@ func (s *Store) Put(ctx context.Context, bucket, unencryptedKey string, data io.
 			}
 
 			var responses []metaclient.BatchResponse
-			if currentSegment == 0 {
+			if streamID.IsZero() {
 				responses, err = s.metainfo.Batch(ctx, beginObjectReq, beginSegment)
 				if err != nil {
 					return Meta{}, err
@@ -224,50 +341,82 @@ func (s *Store) Put(ctx context.Context, bucket, unencryptedKey string, data io.
 				}
 				streamID = objResponse.StreamID
 				objectRS = objResponse.RedundancyStrategy
+
+				if err := journaled.BeginObject(streamID); err != nil {
+					return Meta{}, err
+				}
 			} else {
+				finished, err := uploads.Take()
+				if err != nil {
+					return Meta{}, err
+				}
+				requestsToBatch = append(requestsToBatch, finished...)
+
 				beginSegment.StreamID = streamID
 				responses, err = s.metainfo.Batch(ctx, append(requestsToBatch, beginSegment)...)
-				requestsToBatch = requestsToBatch[:0]
 				if err != nil {
 					return Meta{}, err
 				}
+				if err := journaled.Sent(requestsToBatch...); err != nil {
+					return Meta{}, err
+				}
+				requestsToBatch = requestsToBatch[:0]
 			}
 
-			segResponse, err := responses[1].BeginSegment()
+			segResponse, err := responses[len(responses)-1].BeginSegment()
 			if err != nil {
 				return Meta{}, err
 			}
 			segmentID := segResponse.SegmentID
 			limits := segResponse.Limits
 			piecePrivateKey := segResponse.PiecePrivateKey
-			segmentRS = segResponse.RedundancyStrategy
+			segmentRS := segResponse.RedundancyStrategy
 
 			if segmentRS == (eestream.RedundancyStrategy{}) {
 				segmentRS = objectRS
 			}
-			encSizedReader := SizeReader(transformedReader)
-			uploadResults, err := s.ec.PutSingleResult(ctx, limits, piecePrivateKey, segmentRS, encSizedReader)
-			if err != nil {
-				return Meta{}, err
+			if segmentRS == (eestream.RedundancyStrategy{}) {
+				return Meta{}, errs.New("missing redundancy strategy for segment %d", currentSegment)
 			}
 
-			plainSize := sizeReader.Size()
-			if testuplink.IsWithoutPlainSize(ctx) {
-				plainSize = 0
-			}
+			withoutPlainSize := testuplink.IsWithoutPlainSize(ctx)
+			release := releaseSegment
+			releaseSegment = nil
+			err = uploads.Go(func(ctx context.Context) (metaclient.BatchItem, error) {
+				if release != nil {
+					defer release()
+					ctx = membudget.WithReserved(ctx)
+				}
+
+				encSizedReader := SizeReader(transformedReader)
+				uploadResults, err := s.ec.PutSingleResult(ctx, limits, piecePrivateKey, segmentRS, encSizedReader)
+				if err != nil {
+					return nil, err
+				}
+
+				plainSize := sizeReader.Size()
+				if withoutPlainSize {
+					plainSize = 0
+				}
 
-			requestsToBatch = append(requestsToBatch, &metaclient.CommitSegmentParams{
-				SegmentID:         segmentID,
-				SizeEncryptedData: encSizedReader.Size(),
-				PlainSize:         plainSize,
-				Encryption:        segmentEncryption,
-				UploadResult:      uploadResults,
+				return &metaclient.CommitSegmentParams{
+					SegmentID:         segmentID,
+					SizeEncryptedData: encSizedReader.Size(),
+					PlainSize:         plainSize,
+					Encryption:        segmentEncryption,
+					UploadResult:      uploadResults,
+				}, nil
 			})
+			if err != nil {
+				return Meta{}, err
+			}
+			journaled.AddSegment(sizeReader.Size())
 		} else {
 			data, err := ioutil.ReadAll(peekReader)
 			if err != nil {
 				return Meta{}, err
 			}
+			journaled.AddSegment(int64(len(data)))
 
 			cipherData, err := encryption.Encrypt(data, s.encryptionParameters.CipherSuite, &contentKey, &contentNonce)
 			if err != nil {
@@ -287,7 +436,7 @@ func (s *Store) Put(ctx context.Context, bucket, unencryptedKey string, data io.
 				EncryptedInlineData: cipherData,
 				PlainSize:           plainSize,
 			}
-			if currentSegment == 0 {
+			if streamID.IsZero() {
 				responses, err := s.metainfo.Batch(ctx, beginObjectReq, makeInlineSegment)
 				if err != nil {
 					return Meta{}, err
@@ -297,9 +446,16 @@ func (s *Store) Put(ctx context.Context, bucket, unencryptedKey string, data io.
 					return Meta{}, err
 				}
 				streamID = objResponse.StreamID
+
+				if err := journaled.BeginObject(streamID); err != nil {
+					return Meta{}, err
+				}
+				if err := journaled.Sent(makeInlineSegment); err != nil {
+					return Meta{}, err
+				}
 			} else {
 				makeInlineSegment.StreamID = streamID
-				requestsToBatch = append(requestsToBatch, makeInlineSegment)
+				uploads.Add(makeInlineSegment)
 			}
 		}
 
@@ -312,6 +468,12 @@ func (s *Store) Put(ctx context.Context, bucket, unencryptedKey string, data io.
 		return Meta{}, eofReader.err
 	}
 
+	finished, err := uploads.Finish()
+	if err != nil {
+		return Meta{}, err
+	}
+	requestsToBatch = append(requestsToBatch, finished...)
+
 	metadataBytes, err := metadata.Metadata()
 	if err != nil {
 		return Meta{}, err
@@ -359,6 +521,9 @@ func (s *Store) Put(ctx context.Context, bucket, unencryptedKey string, data io.
 	if err != nil {
 		return Meta{}, err
 	}
+	if err := journaled.Sent(append(requestsToBatch, &commitObject)...); err != nil {
+		return Meta{}, err
+	}
 
 	satStreamID := &pb.SatStreamID{}
 	err = pb.Unmarshal(streamID, satStreamID)
@@ -376,6 +541,51 @@ func (s *Store) Put(ctx context.Context, bucket, unencryptedKey string, data io.
 	return resultMeta, nil
 }
 
+// sectionsReader splits the source into the sections of the segments. The
+// sections are read with ReadAt, so each of them can be read independently
+// of the others. An empty source has a single empty section.
+type sectionsReader struct {
+	source      *io.SectionReader
+	segmentSize int64
+	offset      int64
+	started     bool
+}
+
+// more returns whether there is another section.
+func (sections *sectionsReader) more() bool {
+	return !sections.started || sections.offset < sections.source.Size()
+}
+
+// next returns the reader of the next section.
+func (sections *sectionsReader) next() io.Reader {
+	length := sections.source.Size() - sections.offset
+	if length > sections.segmentSize {
+		length = sections.segmentSize
+	}
+	section := io.NewSectionReader(sections.source, sections.offset, length)
+
+	sections.offset += length
+	sections.started = true
+	return &exactReader{reader: section, remaining: length}
+}
+
+// exactReader fails with io.ErrUnexpectedEOF, when its reader ends before
+// the remaining bytes are read.
+type exactReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+// Read implements io.Reader.
+func (reader *exactReader) Read(p []byte) (n int, err error) {
+	n, err = reader.reader.Read(p)
+	reader.remaining -= int64(n)
+	if errors.Is(err, io.EOF) && reader.remaining > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
 // PutPart uploads single part.
 func (s *Store) PutPart(ctx context.Context, bucket, unencryptedKey string, streamID storj.StreamID, partNumber uint32, eTag ETag, data io.Reader) (_ Part, err error) {
 	defer mon.Task()(&ctx)(&err)
@@ -478,6 +688,7 @@ func (s *Store) PutPart(ctx context.Context, bucket, unencryptedKey string, stre
 				if err != nil {
 					return Part{}, err
 				}
+				progress.TrackerFromContext(ctx).AddSegmentsCommitted(countSegmentCommits(requestsToBatch))
 
 				requestsToBatch = requestsToBatch[:0]
 
@@ -553,6 +764,7 @@ func (s *Store) PutPart(ctx context.Context, bucket, unencryptedKey string, stre
 		if err != nil {
 			return Part{}, err
 		}
+		progress.TrackerFromContext(ctx).AddSegmentsCommitted(countSegmentCommits(requestsToBatch))
 	}
 
 	return Part{
@@ -579,6 +791,48 @@ func encryptETag(etag []byte, encryptionParameters storj.EncryptionParameters, c
 	return encryptedETag, nil
 }
 
+// CompleteSegments returns info with the listing of all segments in the
+// range of info, so that Get doesn't need to list them again. The plain
+// offsets and sizes of the segments are calculated also for objects uploaded
+// by old clients, which stored only the fixed segment size of the object.
+func (s *Store) CompleteSegments(ctx context.Context, info metaclient.DownloadInfo) (_ metaclient.DownloadInfo, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	info.DownloadedSegments = append([]metaclient.DownloadSegmentWithRSResponse(nil), info.DownloadedSegments...)
+	info.ListSegments.Items = append([]metaclient.SegmentListItem(nil), info.ListSegments.Items...)
+	for info.ListSegments.More {
+		var cursor storj.SegmentPosition
+		if len(info.ListSegments.Items) > 0 {
+			last := info.ListSegments.Items[len(info.ListSegments.Items)-1]
+			cursor = last.Position
+		}
+
+		result, err := s.metainfo.ListSegments(ctx, metaclient.ListSegmentsParams{
+			StreamID: info.Object.ID,
+			Cursor:   cursor,
+			Range:    info.Range,
+		})
+		if err != nil {
+			return metaclient.DownloadInfo{}, err
+		}
+
+		info.ListSegments.Items = append(info.ListSegments.Items, result.Items...)
+		info.ListSegments.More = result.More
+	}
+
+	// calculate plain offset and plain size for migrated objects.
+	for i := 0; i < len(info.DownloadedSegments); i++ {
+		seg := &info.DownloadedSegments[i].Info
+		seg.PlainOffset, seg.PlainSize = calculatePlain(*seg.Position, seg.PlainOffset, seg.PlainSize, info.Object)
+	}
+	for i := 0; i < len(info.ListSegments.Items); i++ {
+		seg := &info.ListSegments.Items[i]
+		seg.PlainOffset, seg.PlainSize = calculatePlain(seg.Position, seg.PlainOffset, seg.PlainSize, info.Object)
+	}
+
+	return info, nil
+}
+
 // TODO move it to separate package?
 func deriveETagKey(key *storj.Key) (*storj.Key, error) {
 	return encryption.DeriveKey(key, "storj-etag-v1")
@@ -588,6 +842,19 @@ func deriveETagKey(key *storj.Key) (*storj.Key, error) {
 // and then returns the appropriate data from segments s0/<key>, s1/<key>,
 // ..., l/<key>.
 func (s *Store) Get(ctx context.Context, bucket, unencryptedKey string, info metaclient.DownloadInfo) (rr ranger.Ranger, err error) {
+	return s.GetWithOptions(ctx, bucket, unencryptedKey, info, GetOptions{})
+}
+
+// GetOptions contains additional options for downloading a stream.
+type GetOptions struct {
+	// ReadAhead is the number of segments following the one being read,
+	// which are requested and start downloading in the background.
+	ReadAhead int
+}
+
+// GetWithOptions returns a ranger of the stream like Get, with additional
+// options.
+func (s *Store) GetWithOptions(ctx context.Context, bucket, unencryptedKey string, info metaclient.DownloadInfo, opts GetOptions) (rr ranger.Ranger, err error) {
 	defer mon.Task()(&ctx)(&err)
 
 	object := info.Object
@@ -601,41 +868,14 @@ func (s *Store) Get(ctx context.Context, bucket, unencryptedKey string, info met
 	}
 
 	// download all missing segments
-	if info.ListSegments.More {
-		for info.ListSegments.More {
-			var cursor storj.SegmentPosition
-			if len(info.ListSegments.Items) > 0 {
-				last := info.ListSegments.Items[len(info.ListSegments.Items)-1]
-				cursor = last.Position
-			}
-
-			result, err := s.metainfo.ListSegments(ctx, metaclient.ListSegmentsParams{
-				StreamID: object.ID,
-				Cursor:   cursor,
-				Range:    info.Range,
-			})
-			if err != nil {
-				return nil, err
-			}
-
-			info.ListSegments.Items = append(info.ListSegments.Items, result.Items...)
-			info.ListSegments.More = result.More
-		}
+	info, err = s.CompleteSegments(ctx, info)
+	if err != nil {
+		return nil, err
 	}
 
 	downloaded := info.DownloadedSegments
 	listed := info.ListSegments.Items
 
-	// calculate plain offset and plain size for migrated objects.
-	for i := 0; i < len(info.DownloadedSegments); i++ {
-		seg := &info.DownloadedSegments[i].Info
-		seg.PlainOffset, seg.PlainSize = calculatePlain(*seg.Position, seg.PlainOffset, seg.PlainSize, object)
-	}
-	for i := 0; i < len(info.ListSegments.Items); i++ {
-		seg := &info.ListSegments.Items[i]
-		seg.PlainOffset, seg.PlainSize = calculatePlain(seg.Position, seg.PlainOffset, seg.PlainSize, object)
-	}
-
 	// ensure that the items are correctly sorted
 	sort.Slice(downloaded, func(i, k int) bool {
 		return downloaded[i].Info.PlainOffset < downloaded[k].Info.PlainOffset
@@ -695,6 +935,10 @@ func (s *Store) Get(ctx context.Context, bucket, unencryptedKey string, info met
 				return nil, err
 			}
 
+			if len(segment.Limits) > 0 {
+				decrypted = s.cached(object, *segment.Info.Position, decrypted)
+			}
+
 			rangers = append(rangers, decrypted)
 			offset += segment.Info.PlainSize
 
@@ -707,7 +951,7 @@ func (s *Store) Get(ctx context.Context, bucket, unencryptedKey string, info met
 				return nil, err
 			}
 
-			rangers = append(rangers, &lazySegmentRanger{
+			rangers = append(rangers, s.cached(object, segment.Position, &lazySegmentRanger{
 				metainfo:             s.metainfo,
 				streams:              s,
 				streamID:             object.ID,
@@ -716,7 +960,7 @@ func (s *Store) Get(ctx context.Context, bucket, unencryptedKey string, info met
 				derivedKey:           derivedKey,
 				startingNonce:        &contentNonce,
 				encryptionParameters: object.EncryptionParameters,
-			})
+			}))
 			offset += segment.PlainSize
 
 		default:
@@ -731,9 +975,21 @@ func (s *Store) Get(ctx context.Context, bucket, unencryptedKey string, info met
 		return nil, errs.New("invalid final offset %d; expected %d", offset, object.Size)
 	}
 
+	if opts.ReadAhead > 0 {
+		return newReadAheadRanger(rangers, opts.ReadAhead), nil
+	}
 	return ranger.Concat(rangers...), nil
 }
 
+// cached returns the decrypted ranger rr of the segment at position served
+// through the cache, when the store has one.
+func (s *Store) cached(object storj.Object, position storj.SegmentPosition, rr ranger.Ranger) ranger.Ranger {
+	if s.cache == nil {
+		return rr
+	}
+	return s.cache.Ranger(segmentcache.Key{StreamID: object.ID, Position: position}, object.Modified, rr)
+}
+
 func deriveContentNonce(pos storj.SegmentPosition) (storj.Nonce, error) {
 	// The increment by 1 is to avoid nonce reuse with the metadata encryption,
 	// which is encrypted with the zero nonce.
@@ -901,7 +1157,25 @@ func (s *Store) Ranger(ctx context.Context, response metaclient.DownloadSegmentW
 	}
 
 	rr, err = s.ec.Get(ctx, selected, info.PiecePrivateKey, redundancy, info.EncryptedSize)
-	return rr, err
+	if err != nil {
+		return nil, err
+	}
+	if corruption.ReportFromContext(ctx) != nil && info.Position != nil {
+		rr = &segmentRanger{Ranger: rr, position: *info.Position}
+	}
+	return rr, nil
+}
+
+// segmentRanger adds the position of the segment to the context of its
+// ranges, so that the corrupted pieces can be reported with it.
+type segmentRanger struct {
+	ranger.Ranger
+	position storj.SegmentPosition
+}
+
+// Range implements Ranger.Range.
+func (rr *segmentRanger) Range(ctx context.Context, offset, length int64) (io.ReadCloser, error) {
+	return rr.Ranger.Range(corruption.WithSegment(ctx, rr.position), offset, length)
 }
 
 // invalidRanger is used to mark a range as invalid.
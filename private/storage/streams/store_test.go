@@ -0,0 +1,56 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package streams
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/testrand"
+)
+
+func TestSectionsReader(t *testing.T) {
+	for _, size := range []int{0, 10, 100, 250} {
+		data := testrand.BytesInt(size)
+		sections := &sectionsReader{
+			source:      io.NewSectionReader(bytes.NewReader(data), 0, int64(size)),
+			segmentSize: 100,
+		}
+
+		var readers []io.Reader
+		for sections.more() {
+			readers = append(readers, sections.next())
+		}
+		// an empty source is uploaded as a single empty segment.
+		expected := (size + 99) / 100
+		if size == 0 {
+			expected = 1
+		}
+		require.Len(t, readers, expected)
+
+		// the sections can be read in any order.
+		var read [][]byte
+		for i := len(readers) - 1; i >= 0; i-- {
+			section, err := ioutil.ReadAll(readers[i])
+			require.NoError(t, err)
+			read = append([][]byte{section}, read...)
+		}
+		require.True(t, bytes.Equal(data, bytes.Join(read, nil)))
+	}
+
+	// a source shorter than its size fails the section
+	sections := &sectionsReader{
+		source:      io.NewSectionReader(bytes.NewReader(make([]byte, 150)), 0, 200),
+		segmentSize: 100,
+	}
+	_, err := ioutil.ReadAll(sections.next())
+	require.NoError(t, err)
+	_, err = ioutil.ReadAll(sections.next())
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.False(t, sections.more())
+}
@@ -4,8 +4,11 @@
 package stream
 
 import (
+	"github.com/spacemonkeygo/monkit/v3"
 	"github.com/zeebo/errs"
 )
 
+var mon = monkit.Package()
+
 // Error is the errs class of stream errors.
 var Error = errs.Class("stream")
@@ -5,21 +5,49 @@ package stream
 
 import (
 	"context"
+	"errors"
 	"io"
+	"net"
+	"sync"
+	"syscall"
+	"time"
 
+	"storj.io/common/errs2"
+	"storj.io/common/ranger"
+	"storj.io/common/rpc/rpcstatus"
+	"storj.io/uplink/private/ecclient"
+	"storj.io/uplink/private/eestream"
 	"storj.io/uplink/private/metaclient"
 	"storj.io/uplink/private/storage/streams"
 )
 
-// Download implements Reader, Seeker and Closer for reading from stream.
+// Download implements Reader, Seeker, ReaderAt and Closer for reading from stream.
+//
+// Offsets used with Seek and ReadAt are relative to the start of the
+// downloaded range.
 type Download struct {
 	ctx     context.Context
-	info    metaclient.DownloadInfo
 	streams *streams.Store
 	reader  io.ReadCloser
+	start   int64 // start of the downloaded range in the stream
+	size    int64 // size of the downloaded range
 	offset  int64
 	length  int64
 	closed  bool
+
+	readAhead int
+	// retries is the number of times a failed Read can be resumed.
+	retries int
+	backoff metaclient.ExponentialBackoff
+
+	// mu protects the segment information, which is shared with ReadAt.
+	mu   sync.Mutex
+	info metaclient.DownloadInfo
+	// used is set when the order limits of the downloaded segments in info
+	// have been used.
+	used bool
+	// complete is set when info lists all segments of the downloaded range.
+	complete bool
 }
 
 // NewDownload creates new stream download.
@@ -28,6 +56,7 @@ func NewDownload(ctx context.Context, info metaclient.DownloadInfo, streams *str
 		ctx:     ctx,
 		info:    info,
 		streams: streams,
+		size:    info.Object.Size,
 		length:  info.Object.Size,
 	}
 }
@@ -45,11 +74,36 @@ func NewDownloadRange(ctx context.Context, info metaclient.DownloadInfo, streams
 		ctx:     ctx,
 		info:    info,
 		streams: streams,
+		start:   start,
+		size:    length,
 		offset:  start,
 		length:  length,
 	}
 }
 
+// SetReadAhead sets the number of segments, which are requested and start
+// downloading in the background, while a segment is being read. It applies
+// to Read, but not to ReadAt.
+func (download *Download) SetReadAhead(segments int) {
+	download.readAhead = segments
+}
+
+// SetRetries sets the number of times a Read, which failed in the middle of
+// the stream because of the storage nodes or the network, is resumed. The
+// remaining range is requested again with new order limits after a short
+// backoff, skipping the storage nodes, whose piece downloads failed. It must
+// be called before the first Read.
+func (download *Download) SetRetries(retries int) {
+	download.retries = retries
+	download.backoff = metaclient.ExponentialBackoff{
+		Min: 100 * time.Millisecond,
+		Max: time.Second,
+	}
+	if retries > 0 && ecclient.FailedNodesFromContext(download.ctx) == nil {
+		download.ctx = ecclient.WithFailedNodes(download.ctx, ecclient.NewFailedNodes())
+	}
+}
+
 // Read reads up to len(data) bytes into data.
 //
 // If this is the first call it will read from the beginning of the stream.
@@ -61,23 +115,140 @@ func (download *Download) Read(data []byte) (n int, err error) {
 		return 0, Error.New("already closed")
 	}
 
-	if download.reader == nil {
-		err = download.resetReader()
+	for {
+		if download.reader == nil {
+			err = download.resetReader()
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		if download.length <= 0 {
+			return 0, io.EOF
+		}
+		if download.length < int64(len(data)) {
+			data = data[:download.length]
+		}
+		n, err = download.reader.Read(data)
+		download.length -= int64(n)
+		download.offset += int64(n)
+
+		if !download.shouldResume(err) {
+			return n, err
+		}
+
+		download.retries--
+		mon.Event("download_resumed")
+		download.backoff.Wait()
+
+		// the reader has failed already, so its close error isn't relevant.
+		_ = download.reader.Close()
+		download.reader = nil
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// shouldResume returns whether the reader should be reopened after it
+// failed with err.
+func (download *Download) shouldResume(err error) bool {
+	return err != nil && download.retries > 0 &&
+		download.ctx.Err() == nil && isNodeFailure(err)
+}
+
+// isNodeFailure returns whether err is caused by the storage nodes or by the
+// network, so that the download can succeed with other nodes or later.
+func isNodeFailure(err error) bool {
+	if eestream.ErrNotEnoughPieces.Has(err) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errs2.IsRPC(err, rpcstatus.Unavailable)
+}
+
+// Seek sets the offset for the next Read. The offset is relative to the start
+// of the downloaded range and it can't be moved outside of it.
+//
+// See io.Seeker for more details.
+func (download *Download) Seek(offset int64, whence int) (int64, error) {
+	if download.closed {
+		return 0, Error.New("already closed")
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += download.offset - download.start
+	case io.SeekEnd:
+		offset += download.size
+	default:
+		return 0, Error.New("invalid whence %d", whence)
+	}
+	if offset < 0 || offset > download.size {
+		return 0, Error.New("offset %d outside of the download range", offset)
+	}
+
+	if download.offset-download.start == offset {
+		return offset, nil
+	}
+
+	if download.reader != nil {
+		err := download.reader.Close()
+		download.reader = nil
 		if err != nil {
 			return 0, err
 		}
 	}
 
-	if download.length <= 0 {
+	download.offset = download.start + offset
+	download.length = download.size - offset
+	return offset, nil
+}
+
+// ReadAt reads len(data) bytes into data starting at offset relative to the
+// start of the downloaded range. It doesn't change the offset used by Read
+// and it can be called concurrently with the other methods, except Close.
+//
+// See io.ReaderAt for more details.
+func (download *Download) ReadAt(data []byte, offset int64) (n int, err error) {
+	if download.closed {
+		return 0, Error.New("already closed")
+	}
+	if offset < 0 {
+		return 0, Error.New("negative offset %d", offset)
+	}
+	if offset >= download.size {
 		return 0, io.EOF
 	}
-	if download.length < int64(len(data)) {
-		data = data[:download.length]
+
+	length := int64(len(data))
+	if offset+length > download.size {
+		length = download.size - offset
+	}
+
+	rr, err := download.ranger(0)
+	if err != nil {
+		return 0, err
+	}
+
+	reader, err := rr.Range(download.ctx, download.start+offset, length)
+	if err != nil {
+		return 0, err
 	}
-	n, err = download.reader.Read(data)
-	download.length -= int64(n)
-	download.offset += int64(n)
+	defer func() {
+		if closeErr := reader.Close(); err == nil {
+			err = closeErr
+		}
+	}()
 
+	n, err = io.ReadFull(reader, data[:length])
+	if err == nil && length < int64(len(data)) {
+		err = io.EOF
+	}
 	return n, err
 }
 
@@ -104,9 +275,7 @@ func (download *Download) resetReader() error {
 		}
 	}
 
-	obj := download.info.Object
-
-	rr, err := download.streams.Get(download.ctx, obj.Bucket.Name, obj.Path, download.info)
+	rr, err := download.ranger(download.readAhead)
 	if err != nil {
 		return err
 	}
@@ -118,3 +287,71 @@ func (download *Download) resetReader() error {
 
 	return nil
 }
+
+// ranger returns a ranger of the stream. The segments are listed only once.
+// The first ranger uses the segments downloaded with the object. The order
+// limits of them can be used only once, so the following rangers download all
+// remote segments again. readAhead is the number of segments, which are read
+// in advance.
+func (download *Download) ranger(readAhead int) (ranger.Ranger, error) {
+	download.mu.Lock()
+	defer download.mu.Unlock()
+
+	if !download.complete {
+		info, err := download.streams.CompleteSegments(download.ctx, download.info)
+		if err != nil {
+			return nil, err
+		}
+		download.info, download.complete = info, true
+	}
+
+	info := download.info
+	obj := info.Object
+	rr, err := download.streams.GetWithOptions(download.ctx, obj.Bucket.Name, obj.Path, info, streams.GetOptions{
+		ReadAhead: readAhead,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !download.used {
+		download.used = true
+		download.info = WithoutLimits(info)
+	}
+	return rr, nil
+}
+
+// WithoutLimits returns info where the downloaded remote segments, whose order
+// limits have been used, are moved to the listed segments.
+func WithoutLimits(info metaclient.DownloadInfo) metaclient.DownloadInfo {
+	downloaded := make([]metaclient.DownloadSegmentWithRSResponse, 0, len(info.DownloadedSegments))
+	listed := append([]metaclient.SegmentListItem(nil), info.ListSegments.Items...)
+
+	isListed := make(map[metaclient.SegmentPosition]bool, len(listed))
+	for _, item := range listed {
+		isListed[item.Position] = true
+	}
+
+	for _, segment := range info.DownloadedSegments {
+		if len(segment.Limits) == 0 {
+			// inline segments don't need order limits.
+			downloaded = append(downloaded, segment)
+			continue
+		}
+		if isListed[*segment.Info.Position] {
+			continue
+		}
+
+		listed = append(listed, metaclient.SegmentListItem{
+			Position:          *segment.Info.Position,
+			PlainSize:         segment.Info.PlainSize,
+			PlainOffset:       segment.Info.PlainOffset,
+			EncryptedKeyNonce: segment.Info.SegmentEncryption.EncryptedKeyNonce,
+			EncryptedKey:      segment.Info.SegmentEncryption.EncryptedKey,
+		})
+	}
+
+	info.DownloadedSegments = downloaded
+	info.ListSegments.Items = listed
+	return info
+}
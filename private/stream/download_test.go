@@ -0,0 +1,38 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package stream
+
+import (
+	"context"
+	"io"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/rpc/rpcstatus"
+	"storj.io/uplink/private/eestream"
+	"storj.io/uplink/private/membudget"
+)
+
+func TestIsNodeFailure(t *testing.T) {
+	for _, err := range []error{
+		eestream.Error.Wrap(eestream.ErrNotEnoughPieces.New("failed to download stripe 0")),
+		Error.Wrap(syscall.ECONNRESET),
+		rpcstatus.Error(rpcstatus.Unavailable, "satellite unavailable"),
+	} {
+		require.True(t, isNodeFailure(err), err)
+	}
+
+	for _, err := range []error{
+		io.EOF,
+		io.ErrUnexpectedEOF,
+		context.Canceled,
+		Error.Wrap(membudget.ErrExhausted),
+		eestream.Error.New("decode failure"),
+		rpcstatus.Error(rpcstatus.NotFound, "segment not found"),
+	} {
+		require.False(t, isNodeFailure(err), err)
+	}
+}
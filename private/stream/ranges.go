@@ -0,0 +1,303 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package stream
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/zeebo/errs"
+
+	"storj.io/common/ranger"
+	"storj.io/uplink/private/metaclient"
+	"storj.io/uplink/private/storage/streams"
+)
+
+// Range is a range of a stream.
+type Range struct {
+	Offset int64
+	Length int64
+}
+
+// Ranges reads multiple ranges of a stream.
+//
+// The segments are listed only once for all ranges. The ranges are grouped
+// into spans, which don't share segments, and each span is read with a single
+// request of its segments, so the order limits of the segments downloaded
+// with the object are used for all ranges in them. The data between the
+// ranges of a span is downloaded and discarded, unless it's larger than
+// maxSpanGap. The ranges after such a gap start a new span, which requests
+// the segment it shares with the previous span again.
+type Ranges struct {
+	download *Download
+	ranges   []Range
+	closed   bool
+
+	// rr is the ranger using the order limits of the downloaded segments.
+	rr ranger.Ranger
+
+	spans  []rangeSpan
+	spanOf []int // index of the span of each range, -1 for empty ranges
+
+	current int           // index of the span of the last range
+	reader  io.ReadCloser // reader of the last range read again
+}
+
+// maxSpanGap is the largest gap between the ranges of a span, which is
+// downloaded and discarded rather than requesting the segment again.
+const maxSpanGap = 4 * 1024 * 1024
+
+// rangeSpan is a part of the stream read with a single request.
+type rangeSpan struct {
+	start, limit int64
+	reader       io.ReadCloser
+	offset       int64 // offset of reader in the stream
+	unread       int   // number of the ranges in the span, which weren't opened yet
+	used         bool  // whether the span has been requested
+	shared       bool  // whether the span starts in a segment of the previous span
+}
+
+// NewRanges creates a reader of the ranges of the stream. The ranges must be
+// within the stream and within the range of info.
+func NewRanges(ctx context.Context, info metaclient.DownloadInfo, streams *streams.Store, ranges []Range) *Ranges {
+	return &Ranges{
+		download: NewDownload(ctx, info, streams),
+		ranges:   ranges,
+		current:  -1,
+	}
+}
+
+// Reader returns a reader of the i-th range. The reader is valid until the
+// next call of Reader or Close. Reading the ranges in the order of their
+// offsets is the most efficient, a range, whose span has already been read
+// past its offset, is downloaded again.
+func (ranges *Ranges) Reader(i int) (_ io.Reader, err error) {
+	if ranges.closed {
+		return nil, Error.New("already closed")
+	}
+	if i < 0 || i >= len(ranges.ranges) {
+		return nil, Error.New("invalid range index %d", i)
+	}
+	if err := ranges.init(); err != nil {
+		return nil, err
+	}
+
+	if ranges.reader != nil {
+		err := ranges.reader.Close()
+		ranges.reader = nil
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rng := ranges.ranges[i]
+	index := ranges.spanOf[i]
+	if index < 0 {
+		return eofReader{}, nil
+	}
+	span := &ranges.spans[index]
+
+	if ranges.current >= 0 && ranges.current != index {
+		// close the previous span, once all of its ranges have been read.
+		if err := ranges.closeSpan(ranges.current, false); err != nil {
+			return nil, err
+		}
+	}
+	ranges.current = index
+	if span.unread > 0 {
+		span.unread--
+	}
+
+	if (span.used && span.reader == nil) || span.offset > rng.Offset {
+		// the order limits of the span have already been used.
+		return ranges.readAgain(rng)
+	}
+
+	if span.reader == nil {
+		rr := ranges.rr
+		if span.shared {
+			// the order limits of the shared segment are used by the
+			// previous span.
+			rr, err = ranges.download.ranger(0)
+			if err != nil {
+				return nil, err
+			}
+		}
+		span.reader, err = rr.Range(ranges.download.ctx, span.start, span.limit-span.start)
+		if err != nil {
+			return nil, err
+		}
+		span.offset = span.start
+		span.used = true
+	}
+
+	if skip := rng.Offset - span.offset; skip > 0 {
+		n, err := io.CopyN(ioutil.Discard, span.reader, skip)
+		span.offset += n
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &spanReader{span: span, length: rng.Length}, nil
+}
+
+// init lists the segments and groups the ranges into spans.
+func (ranges *Ranges) init() (err error) {
+	if ranges.rr != nil {
+		return nil
+	}
+
+	rr, err := ranges.download.ranger(0)
+	if err != nil {
+		return err
+	}
+
+	ranges.download.mu.Lock()
+	offsets := segmentOffsets(ranges.download.info)
+	ranges.download.mu.Unlock()
+
+	ranges.spans, ranges.spanOf = groupRanges(ranges.ranges, offsets)
+	ranges.rr = rr
+	return nil
+}
+
+// groupRanges groups the ranges into spans, using the sorted plain offsets of
+// the segments. It returns the spans and the index of the span of each range,
+// which is -1 for empty ranges.
+func groupRanges(rngs []Range, offsets []int64) (spans []rangeSpan, spanOf []int) {
+	segmentOf := func(offset int64) int {
+		return sort.Search(len(offsets), func(k int) bool { return offsets[k] > offset }) - 1
+	}
+
+	order := make([]int, 0, len(rngs))
+	spanOf = make([]int, len(rngs))
+	for i, rng := range rngs {
+		spanOf[i] = -1
+		if rng.Length > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(i, k int) bool {
+		return rngs[order[i]].Offset < rngs[order[k]].Offset
+	})
+
+	for _, i := range order {
+		rng := rngs[i]
+		shared := false
+		if n := len(spans); n > 0 {
+			last := &spans[n-1]
+			shared = rng.Offset < last.limit || segmentOf(rng.Offset) == segmentOf(last.limit-1)
+			if shared && rng.Offset-last.limit <= maxSpanGap {
+				if limit := rng.Offset + rng.Length; limit > last.limit {
+					last.limit = limit
+				}
+				last.unread++
+				spanOf[i] = n - 1
+				continue
+			}
+		}
+		spans = append(spans, rangeSpan{
+			start:  rng.Offset,
+			limit:  rng.Offset + rng.Length,
+			offset: rng.Offset,
+			unread: 1,
+			shared: shared,
+		})
+		spanOf[i] = len(spans) - 1
+	}
+
+	return spans, spanOf
+}
+
+// readAgain returns a reader of the range, which requests new order limits.
+func (ranges *Ranges) readAgain(rng Range) (_ io.Reader, err error) {
+	// the lazy segments keep their order limits, so the ranger isn't reused.
+	rr, err := ranges.download.ranger(0)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges.reader, err = rr.Range(ranges.download.ctx, rng.Offset, rng.Length)
+	if err != nil {
+		return nil, err
+	}
+	return ranges.reader, nil
+}
+
+// closeSpan closes the reader of the span with the index, when all of its
+// ranges have been opened or force is set.
+func (ranges *Ranges) closeSpan(index int, force bool) error {
+	span := &ranges.spans[index]
+	if span.reader == nil || (span.unread > 0 && !force) {
+		return nil
+	}
+	err := span.reader.Close()
+	span.reader = nil
+	return err
+}
+
+// Close closes the readers of the ranges.
+func (ranges *Ranges) Close() error {
+	if ranges.closed {
+		return Error.New("already closed")
+	}
+	ranges.closed = true
+
+	var group errs.Group
+	if ranges.reader != nil {
+		group.Add(ranges.reader.Close())
+	}
+	for i := range ranges.spans {
+		group.Add(ranges.closeSpan(i, true))
+	}
+	return group.Err()
+}
+
+// segmentOffsets returns the sorted plain offsets of the segments in info.
+func segmentOffsets(info metaclient.DownloadInfo) []int64 {
+	offsets := make([]int64, 0, len(info.DownloadedSegments)+len(info.ListSegments.Items))
+	for _, segment := range info.DownloadedSegments {
+		offsets = append(offsets, segment.Info.PlainOffset)
+	}
+	for _, item := range info.ListSegments.Items {
+		offsets = append(offsets, item.PlainOffset)
+	}
+	sort.Slice(offsets, func(i, k int) bool { return offsets[i] < offsets[k] })
+	return offsets
+}
+
+// spanReader reads a range from the reader of its span.
+type spanReader struct {
+	span   *rangeSpan
+	length int64
+}
+
+// Read implements io.Reader.
+func (reader *spanReader) Read(p []byte) (n int, err error) {
+	if reader.length <= 0 {
+		return 0, io.EOF
+	}
+	if reader.span.reader == nil {
+		return 0, Error.New("range reader is no longer valid")
+	}
+	if int64(len(p)) > reader.length {
+		p = p[:reader.length]
+	}
+	n, err = reader.span.reader.Read(p)
+	reader.span.offset += int64(n)
+	reader.length -= int64(n)
+	if err == io.EOF && reader.length > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// eofReader is a reader of an empty range.
+type eofReader struct{}
+
+// Read implements io.Reader.
+func (eofReader) Read(p []byte) (int, error) { return 0, io.EOF }
@@ -0,0 +1,34 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupRanges(t *testing.T) {
+	const segmentSize = 64 * 1024 * 1024
+	offsets := []int64{0, segmentSize, 2 * segmentSize}
+
+	spans, spanOf := groupRanges([]Range{
+		{Offset: 100, Length: 10},
+		{Offset: 1000, Length: 10}, // small gap in the same segment
+		{Offset: 500, Length: 0},
+		{Offset: maxSpanGap + 2000, Length: 10},                 // large gap in the same segment
+		{Offset: segmentSize - 10, Length: 20},                  // crosses into the next segment
+		{Offset: segmentSize + 100, Length: 10},                 // small gap in the shared segment
+		{Offset: 2*segmentSize + maxSpanGap, Length: 10},        // other segment
+		{Offset: 2*segmentSize + 2*maxSpanGap + 10, Length: 10}, // exactly the largest gap
+	}, offsets)
+
+	require.Equal(t, []int{0, 0, -1, 1, 2, 2, 3, 3}, spanOf)
+	require.Equal(t, []rangeSpan{
+		{start: 100, limit: 1010, offset: 100, unread: 2},
+		{start: maxSpanGap + 2000, limit: maxSpanGap + 2010, offset: maxSpanGap + 2000, unread: 1, shared: true},
+		{start: segmentSize - 10, limit: segmentSize + 110, offset: segmentSize - 10, unread: 2, shared: true},
+		{start: 2*segmentSize + maxSpanGap, limit: 2*segmentSize + 2*maxSpanGap + 20, offset: 2*segmentSize + maxSpanGap, unread: 2},
+	}, spans)
+}
@@ -34,6 +34,11 @@ type Upload struct {
 
 // NewUpload creates new stream upload.
 func NewUpload(ctx context.Context, stream *metaclient.MutableStream, streamsStore *streams.Store) *Upload {
+	return NewUploadWithOptions(ctx, stream, streamsStore, streams.PutOptions{})
+}
+
+// NewUploadWithOptions creates new stream upload with the specified options.
+func NewUploadWithOptions(ctx context.Context, stream *metaclient.MutableStream, streamsStore *streams.Store, opts streams.PutOptions) *Upload {
 	reader, writer := io.Pipe()
 
 	upload := Upload{
@@ -44,7 +49,7 @@ func NewUpload(ctx context.Context, stream *metaclient.MutableStream, streamsSto
 	}
 
 	upload.errgroup.Go(func() error {
-		m, err := streamsStore.Put(ctx, stream.BucketName(), stream.Path(), reader, stream, stream.Expires())
+		m, err := streamsStore.PutWithOptions(ctx, stream.BucketName(), stream.Path(), reader, stream, stream.Expires(), opts)
 		if err != nil {
 			err = Error.Wrap(err)
 			return errs.Combine(err, reader.CloseWithError(err))
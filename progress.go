@@ -0,0 +1,70 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package uplink
+
+import (
+	"context"
+	"time"
+
+	"storj.io/uplink/private/progress"
+)
+
+// defaultProgressInterval is used when the progress interval isn't specified.
+const defaultProgressInterval = time.Second
+
+// Progress contains the progress of a transfer.
+type Progress struct {
+	// PlainBytes is the number of bytes of the object data, which have been
+	// accepted by an upload or returned by a download.
+	PlainBytes int64
+	// NodeBytes contains the number of encrypted bytes, which have been sent to
+	// or received from each storage node, keyed by the node ID.
+	NodeBytes map[string]int64
+	// SegmentsCommitted is the number of segments committed by an upload.
+	SegmentsCommitted int64
+	// PiecesUploaded, PiecesFailed and PiecesCanceled are the numbers of
+	// piece uploads of the segments of an upload, which succeeded, failed or
+	// were canceled as configured by UploadLongTail.
+	PiecesUploaded int64
+	PiecesFailed   int64
+	PiecesCanceled int64
+}
+
+// startProgress returns a context which tracks the progress of a transfer and
+// starts reporting it to fn every interval. When fn is nil, the progress isn't
+// tracked.
+func startProgress(ctx context.Context, fn func(Progress), interval time.Duration) (context.Context, *progress.Tracker, *progress.Reporter) {
+	if fn == nil {
+		return ctx, nil, nil
+	}
+
+	tracker := progress.NewTracker()
+	return progress.WithTracker(ctx, tracker), tracker, newProgressReporter(tracker, fn, interval)
+}
+
+// newProgressReporter starts reporting the progress collected by tracker to fn
+// every interval.
+func newProgressReporter(tracker *progress.Tracker, fn func(Progress), interval time.Duration) *progress.Reporter {
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	return progress.NewReporter(tracker, interval, func(snapshot progress.Snapshot) {
+		fn(convertProgress(snapshot))
+	})
+}
+
+func convertProgress(snapshot progress.Snapshot) Progress {
+	nodeBytes := make(map[string]int64, len(snapshot.NodeBytes))
+	for node, n := range snapshot.NodeBytes {
+		nodeBytes[node.String()] = n
+	}
+	return Progress{
+		PlainBytes:        snapshot.PlainBytes,
+		NodeBytes:         nodeBytes,
+		SegmentsCommitted: snapshot.SegmentsCommitted,
+		PiecesUploaded:    snapshot.PiecesUploaded,
+		PiecesFailed:      snapshot.PiecesFailed,
+		PiecesCanceled:    snapshot.PiecesCanceled,
+	}
+}
@@ -14,7 +14,10 @@ import (
 	"storj.io/common/storj"
 	"storj.io/uplink/internal/telemetryclient"
 	"storj.io/uplink/private/ecclient"
+	"storj.io/uplink/private/eestream"
+	"storj.io/uplink/private/membudget"
 	"storj.io/uplink/private/metaclient"
+	"storj.io/uplink/private/segmentcache"
 	"storj.io/uplink/private/storage/streams"
 	"storj.io/uplink/private/testuplink"
 	"storj.io/uplink/private/version"
@@ -33,6 +36,8 @@ type Project struct {
 	access               *Access
 	dialer               rpc.Dialer
 	ec                   ecclient.Client
+	budget               *membudget.Budget
+	cache                *segmentcache.Cache
 	segmentSize          int64
 	encryptionParameters storj.EncryptionParameters
 
@@ -118,13 +123,36 @@ func (config Config) OpenProject(ctx context.Context, access *Access) (project *
 		}
 	}
 
-	ec := ecclient.New(dialer, 0)
+	cache, err := config.Cache.open()
+	if err != nil {
+		return nil, packageError.Wrap(err)
+	}
+	defer func() {
+		// the cache is shared with the other projects, so it must be
+		// released, when this one isn't opened.
+		if err != nil && cache != nil {
+			cache.Close()
+		}
+	}()
+
+	budget := membudget.New(config.MaxTransferMemory, config.FailOnTransferMemoryExhausted)
+	ec := ecclient.New(dialer, 0).
+		WithRateLimits(config.UploadRateLimit.limiter(), config.DownloadRateLimit.limiter()).
+		WithSpill(eestream.SpillConfig{
+			Directory:       config.BufferSpill.Directory,
+			MemoryThreshold: config.BufferSpill.MemoryThreshold,
+		}).
+		WithMemoryBudget(budget).
+		WithLongTail(config.DownloadLongTail.config()).
+		WithUploadLongTail(config.UploadLongTail.config())
 
 	return &Project{
 		config:               config,
 		access:               access,
 		dialer:               dialer,
 		ec:                   ec,
+		budget:               budget,
+		cache:                cache,
 		segmentSize:          segmentsSize,
 		encryptionParameters: encryptionParameters,
 
@@ -133,6 +161,13 @@ func (config Config) OpenProject(ctx context.Context, access *Access) (project *
 	}, nil
 }
 
+// TransferMemoryUsage returns the memory currently reserved by the segment
+// uploads and downloads of the project and the limit set with
+// Config.MaxTransferMemory. Both are zero when there is no limit.
+func (project *Project) TransferMemoryUsage() (used, limit int64) {
+	return project.budget.Used(), project.budget.Limit()
+}
+
 // Close closes the project and all associated resources.
 func (project *Project) Close() (err error) {
 	if project.telemetry != nil {
@@ -148,6 +183,10 @@ func (project *Project) Close() (err error) {
 		err = errs.Combine(err, project.dialer.Pool.Close())
 	}
 
+	if project.cache != nil {
+		project.cache.Close()
+	}
+
 	return packageError.Wrap(err)
 }
 
@@ -175,7 +214,7 @@ func (project *Project) getStreamsStore(ctx context.Context) (_ *streams.Store,
 		return nil, packageError.Wrap(err)
 	}
 
-	return streamStore, nil
+	return streamStore.WithCache(project.cache).WithMemoryBudget(project.budget), nil
 }
 
 func (project *Project) dialMetainfoDB(ctx context.Context) (_ *metaclient.DB, err error) {
@@ -0,0 +1,131 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package testsuite_test
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/memory"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/storj/private/testplanet"
+	"storj.io/uplink"
+)
+
+func TestChecksums(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		expectedData := testrand.Bytes(20 * memory.KiB)
+		expectedSHA256 := sha256.Sum256(expectedData)
+		expectedCRC32C := make([]byte, 4)
+		binary.BigEndian.PutUint32(expectedCRC32C, crc32.Checksum(expectedData, crc32.MakeTable(crc32.Castagnoli)))
+
+		for _, compression := range []uplink.Compression{uplink.CompressionNone, uplink.CompressionGzip} {
+			key := "object-" + string(compression)
+
+			upload, err := project.UploadObject(ctx, "testbucket", key, &uplink.UploadOptions{
+				Compression: compression,
+			})
+			require.NoError(t, err)
+			_, err = upload.Write(expectedData)
+			require.NoError(t, err)
+			require.NoError(t, upload.Commit())
+
+			require.Equal(t, expectedSHA256[:], upload.Info().System.SHA256)
+			require.Equal(t, expectedCRC32C, upload.Info().System.CRC32C)
+
+			stat, err := project.StatObject(ctx, "testbucket", key)
+			require.NoError(t, err)
+			require.Equal(t, expectedSHA256[:], stat.System.SHA256)
+			require.Equal(t, expectedCRC32C, stat.System.CRC32C)
+			require.Empty(t, stat.Custom)
+
+			download, err := project.DownloadObject(ctx, "testbucket", key, nil)
+			require.NoError(t, err)
+			data, err := ioutil.ReadAll(download)
+			require.NoError(t, err)
+			require.NoError(t, download.Close())
+			require.Equal(t, expectedData, data)
+		}
+
+		// uploads without checksums
+		upload, err := project.UploadObject(ctx, "testbucket", "without-checksums", &uplink.UploadOptions{
+			DisableChecksums: true,
+		})
+		require.NoError(t, err)
+		_, err = upload.Write(expectedData)
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+
+		stat, err := project.StatObject(ctx, "testbucket", "without-checksums")
+		require.NoError(t, err)
+		require.Nil(t, stat.System.SHA256)
+		require.Nil(t, stat.System.CRC32C)
+	})
+}
+
+func TestChecksums_Mismatch(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		// multipart uploads don't calculate checksums, so it's possible to
+		// commit an object with a wrong checksum through the custom metadata.
+		info, err := project.BeginUpload(ctx, "testbucket", "object", nil)
+		require.NoError(t, err)
+
+		part, err := project.UploadPart(ctx, "testbucket", "object", info.UploadID, 1)
+		require.NoError(t, err)
+		_, err = part.Write(testrand.Bytes(10 * memory.KiB))
+		require.NoError(t, err)
+		require.NoError(t, part.Commit())
+
+		wrongSHA256 := sha256.Sum256([]byte("wrong"))
+		_, err = project.CommitUpload(ctx, "testbucket", "object", info.UploadID, &uplink.CommitUploadOptions{
+			CustomMetadata: uplink.CustomMetadata{
+				"\x00uplink:sha256": hex.EncodeToString(wrongSHA256[:]),
+			},
+		})
+		require.NoError(t, err)
+
+		download, err := project.DownloadObject(ctx, "testbucket", "object", nil)
+		require.NoError(t, err)
+		_, err = ioutil.ReadAll(download)
+		require.True(t, errors.Is(err, uplink.ErrChecksumMismatch))
+		require.NoError(t, download.Close())
+
+		// ranged downloads are not verified
+		download, err = project.DownloadObject(ctx, "testbucket", "object", &uplink.DownloadOptions{
+			Offset: 10,
+			Length: 100,
+		})
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(download)
+		require.NoError(t, err)
+		require.Len(t, data, 100)
+		require.NoError(t, download.Close())
+	})
+}
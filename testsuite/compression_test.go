@@ -0,0 +1,138 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package testsuite_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/spacemonkeygo/monkit/v3"
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/memory"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/storj/private/testplanet"
+	"storj.io/uplink"
+	"storj.io/uplink/private/testuplink"
+)
+
+func TestCompression(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		newCtx := testuplink.WithMaxSegmentSize(ctx, 100*memory.KiB)
+
+		// compressible data, which doesn't fit into a single frame
+		expectedData := bytes.Repeat(testrand.Bytes(memory.KiB), 3*1024+17)
+
+		upload, err := project.UploadObject(newCtx, "testbucket", "compressed", &uplink.UploadOptions{
+			Compression: uplink.CompressionGzip,
+		})
+		require.NoError(t, err)
+		require.NoError(t, upload.SetCustomMetadata(ctx, uplink.CustomMetadata{"key": "value"}))
+
+		_, err = upload.Write(expectedData)
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+
+		info := upload.Info()
+		require.Equal(t, uplink.CompressionGzip, info.System.Compression)
+		require.EqualValues(t, len(expectedData), info.System.UncompressedLength)
+		require.Less(t, info.System.ContentLength, int64(len(expectedData)))
+
+		assertCompressed := func(object *uplink.Object, custom uplink.CustomMetadata) {
+			require.Equal(t, uplink.CompressionGzip, object.System.Compression)
+			require.EqualValues(t, len(expectedData), object.System.UncompressedLength)
+			require.Equal(t, info.System.ContentLength, object.System.ContentLength)
+			require.Equal(t, custom, object.Custom)
+		}
+
+		stat, err := project.StatObject(ctx, "testbucket", "compressed")
+		require.NoError(t, err)
+		assertCompressed(stat, uplink.CustomMetadata{"key": "value"})
+
+		list := project.ListObjects(ctx, "testbucket", &uplink.ListObjectsOptions{
+			System: true,
+			Custom: true,
+		})
+		require.True(t, list.Next())
+		assertCompressed(list.Item(), uplink.CustomMetadata{"key": "value"})
+		require.False(t, list.Next())
+		require.NoError(t, list.Err())
+
+		size := int64(len(expectedData))
+		for _, tc := range []struct {
+			offset, length int64
+			expectedFrom   int64
+			expectedTo     int64
+		}{
+			{0, -1, 0, size},
+			{100, 10, 100, 110},
+			{memory.MiB.Int64() - 5, 10, memory.MiB.Int64() - 5, memory.MiB.Int64() + 5},
+			{2 * memory.MiB.Int64(), -1, 2 * memory.MiB.Int64(), size},
+			{-1000, -1, size - 1000, size},
+			{size, -1, size, size},
+		} {
+			download, err := project.DownloadObject(ctx, "testbucket", "compressed", &uplink.DownloadOptions{
+				Offset: tc.offset,
+				Length: tc.length,
+			})
+			require.NoError(t, err)
+			assertCompressed(download.Info(), uplink.CustomMetadata{"key": "value"})
+
+			data, err := ioutil.ReadAll(download)
+			require.NoError(t, err)
+			require.NoError(t, download.Close())
+			require.Equal(t, expectedData[tc.expectedFrom:tc.expectedTo], data)
+		}
+
+		download, err := project.DownloadObject(ctx, "testbucket", "compressed", &uplink.DownloadOptions{
+			Length:            -1,
+			SkipDecompression: true,
+		})
+		require.NoError(t, err)
+		stored, err := ioutil.ReadAll(download)
+		require.NoError(t, err)
+		require.NoError(t, download.Close())
+		require.EqualValues(t, info.System.ContentLength, len(stored))
+
+		// updating the custom metadata keeps the compression information
+		err = project.UpdateObjectMetadata(ctx, "testbucket", "compressed", uplink.CustomMetadata{"other": "value"}, nil)
+		require.NoError(t, err)
+
+		stat, err = project.StatObject(ctx, "testbucket", "compressed")
+		require.NoError(t, err)
+		assertCompressed(stat, uplink.CustomMetadata{"other": "value"})
+
+		downloaded, err := planet.Uplinks[0].Download(ctx, planet.Satellites[0], "testbucket", "compressed")
+		require.NoError(t, err)
+		require.Equal(t, expectedData, downloaded)
+
+		// ranged downloads of uncompressed objects don't request the object
+		// info first.
+		uploadObject(t, ctx, project, "testbucket", "plain", memory.KiB)
+		getObject := monkit.Default.ScopeNamed("storj.io/uplink/private/metaclient").FuncNamed("(*Client).GetObject")
+		calls := getObject.Success()
+
+		download, err = project.DownloadObject(ctx, "testbucket", "plain", &uplink.DownloadOptions{
+			Offset: 100,
+			Length: 10,
+		})
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(download)
+		require.NoError(t, err)
+		require.NoError(t, download.Close())
+		require.Len(t, data, 10)
+		require.Equal(t, calls, getObject.Success())
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"io/ioutil"
 	"net"
 	"testing"
 
@@ -19,6 +20,7 @@ import (
 	"storj.io/common/testrand"
 	"storj.io/storj/private/testplanet"
 	"storj.io/uplink"
+	"storj.io/uplink/private/testuplink"
 	"storj.io/uplink/private/transport"
 )
 
@@ -150,3 +152,154 @@ type fakeConnector struct{}
 func (c fakeConnector) DialContext(ctx context.Context, tlsConfig *tls.Config, address string) (rpc.ConnectorConn, error) {
 	return nil, errfakeConnector
 }
+
+func TestBufferSpill(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		config := uplink.Config{
+			BufferSpill: uplink.BufferSpill{
+				Directory:       ctx.Dir("spill"),
+				MemoryThreshold: memory.KiB.Int64(),
+			},
+		}
+
+		project, err := config.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "bucket")
+
+		expectedData := testrand.Bytes(100 * memory.KiB)
+
+		upload, err := project.UploadObject(ctx, "bucket", "object", nil)
+		require.NoError(t, err)
+		_, err = upload.Write(expectedData)
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+
+		download, err := project.DownloadObject(ctx, "bucket", "object", nil)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(download)
+		require.NoError(t, err)
+		require.NoError(t, download.Close())
+		require.Equal(t, expectedData, data)
+	})
+}
+
+func TestMaxTransferMemory(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		config := uplink.Config{
+			MaxTransferMemory:             memory.KiB.Int64(),
+			FailOnTransferMemoryExhausted: true,
+		}
+
+		project, err := config.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "bucket")
+
+		// transfers larger than the limit reserve all of it
+		expectedData := testrand.Bytes(100 * memory.KiB)
+		upload, err := project.UploadObject(ctx, "bucket", "object", nil)
+		require.NoError(t, err)
+		_, err = upload.Write(expectedData)
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+
+		used, limit := project.TransferMemoryUsage()
+		require.Zero(t, used)
+		require.Equal(t, memory.KiB.Int64(), limit)
+
+		// the download reserves the memory as soon as it starts reading
+		download, err := project.DownloadObject(ctx, "bucket", "object", nil)
+		require.NoError(t, err)
+		_, err = download.Read(make([]byte, 1))
+		require.NoError(t, err)
+
+		used, _ = project.TransferMemoryUsage()
+		require.NotZero(t, used)
+
+		upload, err = project.UploadObject(ctx, "bucket", "second", nil)
+		require.NoError(t, err)
+		_, err = upload.Write(expectedData)
+		if err == nil {
+			err = upload.Commit()
+		}
+		require.ErrorIs(t, err, uplink.ErrTransferMemoryExhausted)
+		require.NoError(t, upload.Abort())
+
+		require.NoError(t, download.Close())
+		used, _ = project.TransferMemoryUsage()
+		require.Zero(t, used)
+	})
+}
+
+func TestSegmentCache(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		config := uplink.Config{
+			Cache: uplink.SegmentCache{
+				Directory: ctx.Dir("cache"),
+				MaxSize:   memory.MiB.Int64(),
+			},
+		}
+
+		project, err := config.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "bucket")
+
+		expectedData := testrand.Bytes(100 * memory.KiB)
+
+		upload, err := project.UploadObject(testuplink.WithMaxSegmentSize(ctx, 30*memory.KiB), "bucket", "object", nil)
+		require.NoError(t, err)
+		_, err = upload.Write(expectedData)
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+
+		download := func() []byte {
+			download, err := project.DownloadObject(ctx, "bucket", "object", nil)
+			require.NoError(t, err)
+			data, err := ioutil.ReadAll(download)
+			require.NoError(t, err)
+			require.NoError(t, download.Close())
+			return data
+		}
+
+		require.Equal(t, expectedData, download())
+
+		// the segments are read from the cache without the storage nodes
+		for _, node := range planet.StorageNodes {
+			require.NoError(t, planet.StopPeer(node))
+		}
+		require.Equal(t, expectedData, download())
+
+		// another project of the same directory shares the cache
+		other, err := config.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(other.Close)
+
+		shared, err := other.DownloadObject(ctx, "bucket", "object", nil)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(shared)
+		require.NoError(t, err)
+		require.NoError(t, shared.Close())
+		require.Equal(t, expectedData, data)
+
+		config.Cache.MaxSize *= 2
+		_, err = config.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,487 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package testsuite_test
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/spacemonkeygo/monkit/v3"
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/memory"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/storj/private/testplanet"
+	"storj.io/storj/storage"
+	"storj.io/uplink"
+	"storj.io/uplink/private/testuplink"
+)
+
+func TestDownloadSeekAndReadAt(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		segmentSize := 10 * memory.KiB.Int()
+		expectedData := testrand.Bytes(35 * memory.KiB)
+
+		upload, err := project.UploadObject(testuplink.WithMaxSegmentSize(ctx, memory.Size(segmentSize)), "testbucket", "object", nil)
+		require.NoError(t, err)
+		_, err = upload.Write(expectedData)
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+
+		t.Run("seek", func(t *testing.T) {
+			download, err := project.DownloadObject(ctx, "testbucket", "object", nil)
+			require.NoError(t, err)
+			defer ctx.Check(download.Close)
+
+			buf := make([]byte, 100)
+			_, err = io.ReadFull(download, buf)
+			require.NoError(t, err)
+			require.Equal(t, expectedData[:100], buf)
+
+			for _, offset := range []int64{int64(segmentSize) * 3, 5, int64(segmentSize) - 50, 0} {
+				pos, err := download.Seek(offset, io.SeekStart)
+				require.NoError(t, err)
+				require.Equal(t, offset, pos)
+
+				_, err = io.ReadFull(download, buf)
+				require.NoError(t, err)
+				require.Equal(t, expectedData[offset:offset+100], buf)
+			}
+
+			pos, err := download.Seek(-100, io.SeekEnd)
+			require.NoError(t, err)
+			require.EqualValues(t, len(expectedData)-100, pos)
+			data, err := ioutil.ReadAll(download)
+			require.NoError(t, err)
+			require.Equal(t, expectedData[len(expectedData)-100:], data)
+
+			_, err = download.Seek(1, io.SeekEnd)
+			require.Error(t, err)
+		})
+
+		t.Run("read at", func(t *testing.T) {
+			download, err := project.DownloadObject(ctx, "testbucket", "object", nil)
+			require.NoError(t, err)
+			defer ctx.Check(download.Close)
+
+			offsets := []int64{0, 5, int64(segmentSize) - 50, int64(segmentSize) * 3}
+			results := make([][]byte, len(offsets))
+			errors := make(chan error, len(offsets))
+			for i, offset := range offsets {
+				i, offset := i, offset
+				go func() {
+					results[i] = make([]byte, 200)
+					_, err := download.ReadAt(results[i], offset)
+					errors <- err
+				}()
+			}
+			for range offsets {
+				require.NoError(t, <-errors)
+			}
+			for i, offset := range offsets {
+				require.Equal(t, expectedData[offset:offset+200], results[i])
+			}
+
+			buf := make([]byte, 200)
+			n, err := download.ReadAt(buf, int64(len(expectedData)-100))
+			require.ErrorIs(t, err, io.EOF)
+			require.Equal(t, 100, n)
+			require.Equal(t, expectedData[len(expectedData)-100:], buf[:n])
+
+			// reading at an offset doesn't change the offset of Read
+			data, err := ioutil.ReadAll(download)
+			require.NoError(t, err)
+			require.Equal(t, expectedData, data)
+		})
+
+		t.Run("range", func(t *testing.T) {
+			download, err := project.DownloadObject(ctx, "testbucket", "object", &uplink.DownloadOptions{
+				Offset: int64(segmentSize) + 10,
+				Length: int64(segmentSize),
+			})
+			require.NoError(t, err)
+			defer ctx.Check(download.Close)
+
+			rangeData := expectedData[segmentSize+10 : 2*segmentSize+10]
+
+			pos, err := download.Seek(-10, io.SeekEnd)
+			require.NoError(t, err)
+			require.EqualValues(t, segmentSize-10, pos)
+			data, err := ioutil.ReadAll(download)
+			require.NoError(t, err)
+			require.Equal(t, rangeData[segmentSize-10:], data)
+
+			buf := make([]byte, 100)
+			_, err = download.ReadAt(buf, 50)
+			require.NoError(t, err)
+			require.Equal(t, rangeData[50:150], buf)
+
+			_, err = download.Seek(int64(segmentSize)+1, io.SeekStart)
+			require.Error(t, err)
+		})
+	})
+}
+
+func TestDownloadReadAhead(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		segmentSize := 10 * memory.KiB.Int()
+		expectedData := testrand.Bytes(55 * memory.KiB)
+
+		upload, err := project.UploadObject(testuplink.WithMaxSegmentSize(ctx, memory.Size(segmentSize)), "testbucket", "object", nil)
+		require.NoError(t, err)
+		_, err = upload.Write(expectedData)
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+
+		for _, tc := range []struct {
+			offset, length int64
+		}{
+			{0, -1},
+			{int64(segmentSize) - 1, 2},
+			{int64(segmentSize) + 10, int64(segmentSize) * 3},
+			{-100, -1},
+		} {
+			download, err := project.DownloadObject(ctx, "testbucket", "object", &uplink.DownloadOptions{
+				Offset:    tc.offset,
+				Length:    tc.length,
+				ReadAhead: 2,
+			})
+			require.NoError(t, err)
+			data, err := ioutil.ReadAll(download)
+			require.NoError(t, err)
+			require.NoError(t, download.Close())
+
+			switch {
+			case tc.offset < 0:
+				require.Equal(t, expectedData[int64(len(expectedData))+tc.offset:], data)
+			case tc.length < 0:
+				require.Equal(t, expectedData[tc.offset:], data)
+			default:
+				require.Equal(t, expectedData[tc.offset:tc.offset+tc.length], data)
+			}
+		}
+
+		// closing in the middle of the download stops the segments read ahead
+		download, err := project.DownloadObject(ctx, "testbucket", "object", &uplink.DownloadOptions{
+			Length:    -1,
+			ReadAhead: 3,
+		})
+		require.NoError(t, err)
+		_, err = io.ReadFull(download, make([]byte, 100))
+		require.NoError(t, err)
+		require.NoError(t, download.Close())
+	})
+}
+
+func TestDownloadReadAhead_MaxTransferMemory(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		// every segment download reserves the whole budget, so the segments
+		// can't be read ahead
+		config := uplink.Config{
+			MaxTransferMemory: memory.KiB.Int64(),
+		}
+
+		project, err := config.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		expectedData := testrand.Bytes(55 * memory.KiB)
+		upload, err := project.UploadObject(testuplink.WithMaxSegmentSize(ctx, 10*memory.KiB), "testbucket", "object", nil)
+		require.NoError(t, err)
+		_, err = upload.Write(expectedData)
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+
+		download, err := project.DownloadObject(ctx, "testbucket", "object", &uplink.DownloadOptions{
+			Length:    -1,
+			ReadAhead: 3,
+		})
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(download)
+		require.NoError(t, err)
+		require.NoError(t, download.Close())
+		require.Equal(t, expectedData, data)
+
+		used, _ := project.TransferMemoryUsage()
+		require.Zero(t, used)
+	})
+}
+
+func TestDownloadLongTail(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		expectedData := testrand.Bytes(50 * memory.KiB)
+		err := planet.Uplinks[0].Upload(ctx, planet.Satellites[0], "testbucket", "object", expectedData)
+		require.NoError(t, err)
+
+		for _, longTail := range []uplink.DownloadLongTail{
+			{ExtraPieces: -1},
+			{ExtraPieces: 0},
+			{ExtraPieces: 1, HedgeAfter: time.Millisecond},
+			{ExtraPieces: -1, CancelSlow: true},
+		} {
+			longTail := longTail
+			download, err := project.DownloadObject(ctx, "testbucket", "object", &uplink.DownloadOptions{
+				LongTail: &longTail,
+			})
+			require.NoError(t, err)
+			data, err := ioutil.ReadAll(download)
+			require.NoError(t, err)
+			require.NoError(t, download.Close())
+			require.Equal(t, expectedData, data)
+		}
+	})
+}
+
+func TestDownloadVerifyAllShares(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+		Reconfigure: testplanet.Reconfigure{
+			// store all pieces, so that a corrupted one can be corrected
+			Satellite: testplanet.ReconfigureRS(2, 3, 4, 4),
+		},
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		expectedData := testrand.Bytes(50 * memory.KiB)
+		err := planet.Uplinks[0].Upload(ctx, planet.Satellites[0], "testbucket", "object", expectedData)
+		require.NoError(t, err)
+
+		download, err := project.DownloadObject(ctx, "testbucket", "object", &uplink.DownloadOptions{
+			VerifyAllShares: true,
+		})
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(download)
+		require.NoError(t, err)
+		require.Empty(t, download.CorruptedPieces())
+		require.NoError(t, download.Close())
+		require.Equal(t, expectedData, data)
+
+		segments, err := planet.Satellites[0].Metabase.DB.TestingAllSegments(ctx)
+		require.NoError(t, err)
+		require.Len(t, segments, 1)
+
+		// corrupt the end of a stored piece
+		piece := segments[0].Pieces[0]
+		node := planet.FindNode(piece.StorageNode)
+		require.NotNil(t, node)
+
+		blobRef := storage.BlobRef{
+			Namespace: planet.Satellites[0].ID().Bytes(),
+			Key:       segments[0].RootPieceID.Derive(piece.StorageNode, int32(piece.Number)).Bytes(),
+		}
+		reader, err := node.Storage2.BlobsCache.Open(ctx, blobRef)
+		require.NoError(t, err)
+		pieceData, err := ioutil.ReadAll(reader)
+		require.NoError(t, err)
+		require.NoError(t, reader.Close())
+		require.NoError(t, node.Storage2.BlobsCache.Delete(ctx, blobRef))
+
+		pieceData[len(pieceData)-1]++
+		writer, err := node.Storage2.BlobsCache.Create(ctx, blobRef, int64(len(pieceData)))
+		require.NoError(t, err)
+		_, err = writer.Write(pieceData)
+		require.NoError(t, err)
+		require.NoError(t, writer.Commit(ctx))
+
+		download, err = project.DownloadObject(ctx, "testbucket", "object", &uplink.DownloadOptions{
+			VerifyAllShares: true,
+		})
+		require.NoError(t, err)
+		data, err = ioutil.ReadAll(download)
+		require.NoError(t, err)
+		require.NoError(t, download.Close())
+		require.Equal(t, expectedData, data)
+		require.Equal(t, []uplink.CorruptedPiece{{
+			SegmentPart:  segments[0].Position.Part,
+			SegmentIndex: segments[0].Position.Index,
+			PieceNum:     int(piece.Number),
+			NodeID:       piece.StorageNode.String(),
+		}}, download.CorruptedPieces())
+	})
+}
+
+func TestDownloadRanges(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		segmentSize := 10 * memory.KiB.Int()
+		expectedData := testrand.Bytes(35 * memory.KiB)
+
+		upload, err := project.UploadObject(testuplink.WithMaxSegmentSize(ctx, memory.Size(segmentSize)), "testbucket", "object", nil)
+		require.NoError(t, err)
+		_, err = upload.Write(expectedData)
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+
+		size := int64(len(expectedData))
+		ranges := []uplink.Range{
+			{Offset: 10, Length: 100},
+			{Offset: 500, Length: 100},                     // same segment as the previous one
+			{Offset: int64(segmentSize) - 50, Length: 100}, // crosses the segment boundary
+			{Offset: int64(segmentSize) * 3, Length: 0},
+			{Offset: 20, Length: 10}, // already read past
+			{Offset: -100, Length: -1},
+			{Offset: int64(segmentSize) * 2, Length: -1},
+		}
+		expected := []uplink.Range{
+			{Offset: 10, Length: 100},
+			{Offset: 500, Length: 100},
+			{Offset: int64(segmentSize) - 50, Length: 100},
+			{Offset: int64(segmentSize) * 3, Length: 0},
+			{Offset: 20, Length: 10},
+			{Offset: size - 100, Length: 100},
+			{Offset: int64(segmentSize) * 2, Length: size - int64(segmentSize)*2},
+		}
+
+		download, err := project.DownloadRanges(ctx, "testbucket", "object", ranges, nil)
+		require.NoError(t, err)
+		require.EqualValues(t, size, download.Info().System.ContentLength)
+
+		for _, rng := range expected {
+			require.True(t, download.Next())
+			require.Equal(t, rng, download.Range())
+
+			data, err := ioutil.ReadAll(download)
+			require.NoError(t, err)
+			require.Equal(t, expectedData[rng.Offset:rng.Offset+rng.Length], data)
+		}
+		require.False(t, download.Next())
+		require.NoError(t, download.Err())
+		require.NoError(t, download.Close())
+
+		_, err = project.DownloadRanges(ctx, "testbucket", "object", nil, nil)
+		require.Error(t, err)
+
+		_, err = project.DownloadRanges(ctx, "testbucket", "object", []uplink.Range{{Offset: -1, Length: 1}}, nil)
+		require.Error(t, err)
+
+		_, err = project.DownloadRanges(ctx, "testbucket", "missing", ranges, nil)
+		require.ErrorIs(t, err, uplink.ErrObjectNotFound)
+	})
+}
+
+func TestDownloadRetries(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+		Reconfigure: testplanet.Reconfigure{
+			Satellite: testplanet.ReconfigureRS(2, 3, 4, 4),
+		},
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		resumed := monkit.Default.ScopeNamed("storj.io/uplink/private/stream").Meter("download_resumed")
+
+		segmentSize := 10 * memory.KiB.Int()
+		expectedData := testrand.Bytes(35 * memory.KiB)
+
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		upload, err := project.UploadObject(testuplink.WithMaxSegmentSize(ctx, memory.Size(segmentSize)), "testbucket", "object", nil)
+		require.NoError(t, err)
+		_, err = upload.Write(expectedData)
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+
+		// download reads the first segment and calls fail, before reading the
+		// rest of the object.
+		download := func(retries int, fail func()) ([]byte, error) {
+			config := uplink.Config{
+				DownloadRetries: retries,
+			}
+			project, err := config.OpenProject(ctx, planet.Uplinks[0].Access[planet.Satellites[0].ID()])
+			require.NoError(t, err)
+			defer ctx.Check(project.Close)
+
+			download, err := project.DownloadObject(ctx, "testbucket", "object", nil)
+			require.NoError(t, err)
+			defer func() { _ = download.Close() }()
+
+			buf := make([]byte, segmentSize)
+			if _, err := io.ReadFull(download, buf); err != nil {
+				return nil, err
+			}
+			fail()
+
+			data, err := ioutil.ReadAll(download)
+			return append(buf, data...), err
+		}
+
+		stop := func(nodes ...*testplanet.StorageNode) func() {
+			return func() {
+				for _, node := range nodes {
+					require.NoError(t, planet.StopPeer(node))
+				}
+			}
+		}
+
+		// the download continues after a storage node fails
+		data, err := download(3, stop(planet.StorageNodes[0]))
+		require.NoError(t, err)
+		require.Equal(t, expectedData, data)
+
+		// there aren't enough pieces left without the failed nodes and the
+		// download isn't resumed without retries
+		before := resumed.Total()
+		_, err = download(0, stop(planet.StorageNodes[1:3]...))
+		require.Error(t, err)
+		require.Zero(t, resumed.Total()-before)
+
+		// the download is resumed until the retries run out
+		before = resumed.Total()
+		_, err = download(3, func() {})
+		require.Error(t, err)
+		require.EqualValues(t, 3, resumed.Total()-before)
+	})
+}
@@ -83,7 +83,8 @@ func TestMoveObject(t *testing.T) {
 
 				movedObj, err := project.StatObject(ctx, tc.NewBucket, tc.NewKey)
 				require.NoError(t, err)
-				obj.Key = tc.NewKey // for easy compare
+				obj.Key = tc.NewKey              // for easy compare
+				obj.StreamID = movedObj.StreamID // the stream ID is bound to the key
 				require.Equal(t, obj, movedObj)
 
 				_, err = project.StatObject(ctx, tc.Bucket, tc.Key)
@@ -93,6 +94,57 @@ func TestMoveObject(t *testing.T) {
 	})
 }
 
+func TestMoveObject_Precondition(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		err := planet.Uplinks[0].Upload(ctx, planet.Satellites[0], "testbucket", "key", testrand.Bytes(memory.KiB))
+		require.NoError(t, err)
+
+		obj, err := project.StatObject(ctx, "testbucket", "key")
+		require.NoError(t, err)
+
+		err = project.MoveObject(ctx, "testbucket", "key", "testbucket", "new-key", &uplink.MoveObjectOptions{
+			Version: obj.Version + 1,
+		})
+		require.True(t, errors.Is(err, uplink.ErrPreconditionFailed))
+
+		_, err = project.StatObject(ctx, "testbucket", "new-key")
+		require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+
+		// uploading again replaces the expected stream
+		err = planet.Uplinks[0].Upload(ctx, planet.Satellites[0], "testbucket", "key", testrand.Bytes(memory.KiB))
+		require.NoError(t, err)
+
+		err = project.MoveObject(ctx, "testbucket", "key", "testbucket", "new-key", &uplink.MoveObjectOptions{
+			StreamID: obj.StreamID,
+		})
+		require.True(t, errors.Is(err, uplink.ErrPreconditionFailed))
+
+		_, err = project.StatObject(ctx, "testbucket", "new-key")
+		require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+
+		obj, err = project.StatObject(ctx, "testbucket", "key")
+		require.NoError(t, err)
+
+		err = project.MoveObject(ctx, "testbucket", "key", "testbucket", "new-key", &uplink.MoveObjectOptions{
+			Version:  obj.Version,
+			StreamID: obj.StreamID,
+		})
+		require.NoError(t, err)
+
+		_, err = project.StatObject(ctx, "testbucket", "new-key")
+		require.NoError(t, err)
+	})
+}
+
 func TestMoveObject_Errors(t *testing.T) {
 	testplanet.Run(t, testplanet.Config{
 		SatelliteCount:   1,
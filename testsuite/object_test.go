@@ -595,6 +595,148 @@ func TestVeryLongDownload(t *testing.T) {
 	})
 }
 
+func TestObjectVersions(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		versions := func() []*uplink.Object {
+			var objects []*uplink.Object
+			iterator := project.ListObjectVersions(ctx, "testbucket", "test.dat")
+			for iterator.Next() {
+				objects = append(objects, iterator.Item())
+			}
+			require.NoError(t, iterator.Err())
+			return objects
+		}
+		require.Empty(t, versions())
+
+		uploadObject(t, ctx, project, "testbucket", "test.dat", 10*memory.KiB)
+
+		first, err := project.StatObject(ctx, "testbucket", "test.dat")
+		require.NoError(t, err)
+		require.NotZero(t, first.Version)
+		require.NotEmpty(t, first.StreamID)
+
+		obj, err := project.StatObjectVersion(ctx, "testbucket", "test.dat", first.Version)
+		require.NoError(t, err)
+		require.Equal(t, first.Version, obj.Version)
+		require.Equal(t, first.StreamID, obj.StreamID)
+
+		listed := versions()
+		require.Len(t, listed, 1)
+		require.Equal(t, first.Version, listed[0].Version)
+		require.Equal(t, first.StreamID, listed[0].StreamID)
+
+		objects := listObjects(ctx, t, project, "testbucket", nil)
+		require.True(t, objects.Next())
+		require.Equal(t, first.Version, objects.Item().Version)
+
+		// uploading again creates a new version
+		uploadObject(t, ctx, project, "testbucket", "test.dat", 10*memory.KiB)
+
+		second, err := project.StatObject(ctx, "testbucket", "test.dat")
+		require.NoError(t, err)
+		require.NotEqual(t, first.Version, second.Version)
+		require.NotEqual(t, first.StreamID, second.StreamID)
+
+		_, err = project.StatObjectVersion(ctx, "testbucket", "test.dat", first.Version)
+		require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+
+		_, err = project.DownloadObject(ctx, "testbucket", "test.dat", &uplink.DownloadOptions{
+			Version: first.Version,
+		})
+		require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+
+		download, err := project.DownloadObject(ctx, "testbucket", "test.dat", &uplink.DownloadOptions{
+			Version: second.Version,
+		})
+		require.NoError(t, err)
+		_, err = ioutil.ReadAll(download)
+		require.NoError(t, err)
+		require.NoError(t, download.Close())
+		require.Equal(t, second.StreamID, download.Info().StreamID)
+
+		listed = versions()
+		require.Len(t, listed, 1)
+		require.Equal(t, second.Version, listed[0].Version)
+	})
+}
+
+func TestObjectPreconditions(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		uploadObject(t, ctx, project, "testbucket", "test.dat", memory.KiB)
+		first, err := project.StatObject(ctx, "testbucket", "test.dat")
+		require.NoError(t, err)
+
+		err = project.UpdateObjectMetadata(ctx, "testbucket", "test.dat", uplink.CustomMetadata{"key": "value"}, &uplink.UploadObjectMetadataOptions{
+			Version:  first.Version,
+			StreamID: first.StreamID,
+		})
+		require.NoError(t, err)
+
+		// uploading again replaces the expected version
+		uploadObject(t, ctx, project, "testbucket", "test.dat", memory.KiB)
+		second, err := project.StatObject(ctx, "testbucket", "test.dat")
+		require.NoError(t, err)
+
+		err = project.UpdateObjectMetadata(ctx, "testbucket", "test.dat", uplink.CustomMetadata{"key": "other"}, &uplink.UploadObjectMetadataOptions{
+			StreamID: first.StreamID,
+		})
+		require.True(t, errors.Is(err, uplink.ErrPreconditionFailed))
+
+		obj, err := project.StatObject(ctx, "testbucket", "test.dat")
+		require.NoError(t, err)
+		require.Empty(t, obj.Custom)
+
+		err = project.UpdateObjectMetadata(ctx, "testbucket", "test.dat", uplink.CustomMetadata{"key": "other"}, &uplink.UploadObjectMetadataOptions{
+			Version:  second.Version,
+			StreamID: second.StreamID,
+		})
+		require.NoError(t, err)
+
+		obj, err = project.StatObject(ctx, "testbucket", "test.dat")
+		require.NoError(t, err)
+		require.Equal(t, uplink.CustomMetadata{"key": "other"}, obj.Custom)
+
+		_, err = project.DeleteObjectWithOptions(ctx, "testbucket", "test.dat", &uplink.DeleteObjectOptions{
+			StreamID: first.StreamID,
+		})
+		require.True(t, errors.Is(err, uplink.ErrPreconditionFailed))
+
+		_, err = project.StatObject(ctx, "testbucket", "test.dat")
+		require.NoError(t, err)
+
+		deleted, err := project.DeleteObjectWithOptions(ctx, "testbucket", "test.dat", &uplink.DeleteObjectOptions{
+			Version:  second.Version,
+			StreamID: second.StreamID,
+		})
+		require.NoError(t, err)
+		require.Equal(t, second.Version, deleted.Version)
+		require.Equal(t, second.StreamID, deleted.StreamID)
+
+		_, err = project.DeleteObjectWithOptions(ctx, "testbucket", "test.dat", &uplink.DeleteObjectOptions{
+			Version: second.Version,
+		})
+		require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+	})
+}
+
 func assertObject(t *testing.T, obj *uplink.Object, expectedKey string) {
 	assert.Equal(t, expectedKey, obj.Key)
 	assert.WithinDuration(t, time.Now(), obj.System.Created, 10*time.Second)
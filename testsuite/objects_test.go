@@ -7,13 +7,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path"
+	"sort"
 	"testing"
+	"time"
 
+	"github.com/spacemonkeygo/monkit/v3"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"storj.io/common/memory"
 	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
 	"storj.io/storj/private/testplanet"
 	"storj.io/uplink"
 	"storj.io/uplink/private/testuplink"
@@ -341,6 +346,271 @@ func TestListObjects_Paging(t *testing.T) {
 	})
 }
 
+func TestListObjects_Reverse(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 0,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		for i := 0; i < 17; i++ {
+			uploadObject(t, ctx, project, "testbucket", fmt.Sprintf("%d/%d.dat", i, i), 1)
+		}
+		uploadObject(t, ctx, project, "testbucket", "5/other.dat", 1)
+
+		collect := func(ctx context.Context, options *uplink.ListObjectsOptions) []string {
+			var keys []string
+			list := listObjects(ctx, t, project, "testbucket", options)
+			for list.Next() {
+				keys = append(keys, list.Item().Key)
+			}
+			require.NoError(t, list.Err())
+			return keys
+		}
+		reversed := func(keys []string) []string {
+			result := make([]string, 0, len(keys))
+			for i := len(keys) - 1; i >= 0; i-- {
+				result = append(result, keys[i])
+			}
+			return result
+		}
+
+		pagedCtx := testuplink.WithListLimit(ctx, 3)
+		for _, recursive := range []bool{false, true} {
+			forward := collect(ctx, &uplink.ListObjectsOptions{Recursive: recursive})
+			if recursive {
+				require.Len(t, forward, 18)
+			} else {
+				require.Len(t, forward, 17)
+			}
+
+			require.Equal(t, reversed(forward), collect(ctx, &uplink.ListObjectsOptions{
+				Recursive: recursive,
+				Reverse:   true,
+			}))
+			listed := monkit.Default.ScopeNamed("storj.io/uplink/private/metaclient").FuncNamed("(*Client).ListObjects")
+			calls := listed.Success()
+			require.Equal(t, reversed(forward), collect(pagedCtx, &uplink.ListObjectsOptions{
+				Recursive: recursive,
+				Reverse:   true,
+			}))
+			// the first page lists all 6 pages from the satellite and each of
+			// the 5 following pages only the 2 pages before its cursor.
+			require.LessOrEqual(t, listed.Success()-calls, int64(6+5*2))
+
+			// the previous page of the objects before the cursor
+			require.Equal(t, reversed(forward[:10]), collect(pagedCtx, &uplink.ListObjectsOptions{
+				Recursive: recursive,
+				Reverse:   true,
+				Cursor:    forward[10],
+			}))
+			require.Empty(t, collect(ctx, &uplink.ListObjectsOptions{
+				Recursive: recursive,
+				Reverse:   true,
+				Cursor:    forward[0],
+			}))
+		}
+	})
+}
+
+func TestListObjects_Filter(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 0,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		upload := func(key string, size int, expires time.Time) *uplink.Object {
+			upload, err := project.UploadObject(ctx, "testbucket", key, &uplink.UploadOptions{
+				Expires: expires,
+			})
+			require.NoError(t, err)
+			_, err = upload.Write(testrand.BytesInt(size))
+			require.NoError(t, err)
+			require.NoError(t, upload.Commit())
+			return upload.Info()
+		}
+
+		now := time.Now()
+		first := upload("dir/first", 1, time.Time{})
+		upload("dir/second", 100, now.Add(time.Hour))
+		upload("third", 1000, now.Add(48*time.Hour))
+		last := upload("fourth", 10, time.Time{})
+
+		for _, tc := range []struct {
+			filter uplink.ObjectFilter
+			keys   []string
+		}{
+			{uplink.ObjectFilter{}, []string{"dir/first", "dir/second", "fourth", "third"}},
+			{uplink.ObjectFilter{CreatedAfter: first.System.Created}, []string{"dir/second", "fourth", "third"}},
+			{uplink.ObjectFilter{CreatedBefore: last.System.Created}, []string{"dir/first", "dir/second", "third"}},
+			{uplink.ObjectFilter{MinSize: 10}, []string{"dir/second", "fourth", "third"}},
+			{uplink.ObjectFilter{MinSize: 10, MaxSize: 100}, []string{"dir/second", "fourth"}},
+			{uplink.ObjectFilter{Expiration: uplink.WithExpiration}, []string{"dir/second", "third"}},
+			{uplink.ObjectFilter{Expiration: uplink.WithoutExpiration}, []string{"dir/first", "fourth"}},
+			{uplink.ObjectFilter{ExpiresWithin: 24 * time.Hour}, []string{"dir/second"}},
+		} {
+			tc := tc
+			// a small page size checks, that the filtering works across pages.
+			list := listObjects(testuplink.WithListLimit(ctx, 1), t, project, "testbucket", &uplink.ListObjectsOptions{
+				Recursive: true,
+				Filter:    &tc.filter,
+			})
+
+			var keys []string
+			for list.Next() {
+				keys = append(keys, list.Item().Key)
+				require.Zero(t, list.Item().System, "system metadata is included only when requested")
+			}
+			require.NoError(t, list.Err())
+			sort.Strings(keys)
+			require.Equal(t, tc.keys, keys, tc.filter)
+		}
+
+		// prefixes aren't filtered
+		list := listObjects(ctx, t, project, "testbucket", &uplink.ListObjectsOptions{
+			System: true,
+			Filter: &uplink.ObjectFilter{MinSize: 1000},
+		})
+		var keys []string
+		for list.Next() {
+			keys = append(keys, list.Item().Key)
+		}
+		require.NoError(t, list.Err())
+		sort.Strings(keys)
+		require.Equal(t, []string{"dir/", "third"}, keys)
+	})
+}
+
+func TestListObjects_Pattern(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 0,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		for _, key := range []string{
+			"logs/2026-01/app-1.json",
+			"logs/2026-01/app-12.json",
+			"logs/2026-01/sub/app-4.json",
+			"logs/2026-02/app-2.json",
+			"logs/2025-12/app-3.json",
+			"other/app-5.json",
+		} {
+			uploadObject(t, ctx, project, "testbucket", key, 1)
+		}
+
+		collect := func(options *uplink.ListObjectsOptions) []string {
+			var keys []string
+			list := listObjects(testuplink.WithListLimit(ctx, 2), t, project, "testbucket", options)
+			for list.Next() {
+				keys = append(keys, list.Item().Key)
+			}
+			require.NoError(t, list.Err())
+			sort.Strings(keys)
+			return keys
+		}
+
+		require.Equal(t, []string{
+			"logs/2026-01/app-1.json",
+			"logs/2026-02/app-2.json",
+		}, collect(&uplink.ListObjectsOptions{Pattern: "logs/2026-*/app-?.json"}))
+
+		require.Equal(t, []string{
+			"logs/2026-01/app-1.json",
+			"logs/2026-01/app-12.json",
+		}, collect(&uplink.ListObjectsOptions{Prefix: "logs/", Pattern: "2026-0[1]/*"}))
+
+		require.Equal(t, []string{
+			"logs/2025-12/app-3.json",
+			"logs/2026-01/app-1.json",
+			"logs/2026-02/app-2.json",
+		}, collect(&uplink.ListObjectsOptions{Pattern: "*/*/app-?.json"}))
+
+		require.Empty(t, collect(&uplink.ListObjectsOptions{Pattern: "missing/*"}))
+
+		list := listObjects(ctx, t, project, "testbucket", &uplink.ListObjectsOptions{Pattern: "logs/["})
+		require.False(t, list.Next())
+		require.ErrorIs(t, list.Err(), path.ErrBadPattern)
+		require.Contains(t, list.Err().Error(), `invalid pattern "logs/["`)
+	})
+}
+
+func TestListObjects_Delimiter(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 0,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		for _, key := range []string{
+			"logs-2026-01",
+			"logs-2026-02",
+			"logs-2025-12",
+			"logsx",
+			"dir/a-1",
+			"dir/a-2",
+			"dir/b",
+		} {
+			uploadObject(t, ctx, project, "testbucket", key, 1)
+		}
+
+		type item struct {
+			Key      string
+			IsPrefix bool
+		}
+		collect := func(options *uplink.ListObjectsOptions) []item {
+			var items []item
+			list := listObjects(testuplink.WithListLimit(ctx, 2), t, project, "testbucket", options)
+			for list.Next() {
+				items = append(items, item{list.Item().Key, list.Item().IsPrefix})
+			}
+			require.NoError(t, list.Err())
+			sort.Slice(items, func(i, k int) bool { return items[i].Key < items[k].Key })
+			return items
+		}
+
+		// the slashes don't collapse the keys
+		require.Equal(t, []item{
+			{"dir/a-", true},
+			{"dir/b", false},
+			{"logs-", true},
+			{"logsx", false},
+		}, collect(&uplink.ListObjectsOptions{Delimiter: "-"}))
+
+		require.Equal(t, []item{
+			{"logs-2025-", true},
+			{"logs-2026-", true},
+		}, collect(&uplink.ListObjectsOptions{Prefix: "logs-", Delimiter: "-"}))
+
+		require.Equal(t, []item{
+			{"dir/a-", true},
+			{"dir/b", false},
+		}, collect(&uplink.ListObjectsOptions{Prefix: "dir/", Delimiter: "-"}))
+
+		require.Equal(t, []item{
+			{"logs-2026-01", false},
+			{"logs-2026-02", false},
+		}, collect(&uplink.ListObjectsOptions{Prefix: "logs-2026", Delimiter: "-", Recursive: true}))
+	})
+}
+
 func TestListObjects_TwoObjectsWithDiffPassphrase(t *testing.T) {
 	testplanet.Run(t, testplanet.Config{
 		SatelliteCount:   1,
@@ -490,3 +760,70 @@ func assertNoNextObject(t *testing.T, list *uplink.ObjectIterator) {
 	require.NoError(t, list.Err())
 	require.Nil(t, list.Item())
 }
+
+func TestWalk(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 0,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		expectedKeys := map[string]bool{}
+		for _, key := range []string{
+			"a", "b/1", "b/2", "b/c/1", "b/c/d/1", "e/f/g/1", "e/2", "h//1",
+		} {
+			uploadObject(t, ctx, project, "testbucket", key, 1)
+			expectedKeys[key] = true
+		}
+
+		walk := func(prefix string, options *uplink.WalkOptions) map[string]int {
+			keys := map[string]int{}
+			err := project.Walk(ctx, "testbucket", prefix, func(object *uplink.Object) error {
+				require.False(t, object.IsPrefix)
+				keys[object.Key]++
+				return nil
+			}, options)
+			require.NoError(t, err)
+			return keys
+		}
+
+		for _, concurrency := range []int{0, 1, 3} {
+			keys := walk("", &uplink.WalkOptions{Concurrency: concurrency})
+			require.Len(t, keys, len(expectedKeys))
+			for key, count := range keys {
+				require.True(t, expectedKeys[key], key)
+				require.Equal(t, 1, count, key)
+			}
+		}
+
+		require.Equal(t, map[string]int{"b/1": 1, "b/2": 1, "b/c/1": 1, "b/c/d/1": 1}, walk("b/", nil))
+		require.Empty(t, walk("x/", nil))
+
+		// the system metadata is included when requested
+		err := project.Walk(ctx, "testbucket", "", func(object *uplink.Object) error {
+			require.EqualValues(t, 1, object.System.ContentLength)
+			return nil
+		}, &uplink.WalkOptions{System: true})
+		require.NoError(t, err)
+
+		// an error stops the walk
+		stop := errors.New("stop")
+		calls := 0
+		err = project.Walk(ctx, "testbucket", "", func(object *uplink.Object) error {
+			calls++
+			return stop
+		}, nil)
+		require.ErrorIs(t, err, stop)
+		require.Equal(t, 1, calls)
+
+		err = project.Walk(ctx, "testbucket", "b", func(object *uplink.Object) error { return nil }, nil)
+		require.Error(t, err)
+
+		err = project.Walk(ctx, "missing", "", func(object *uplink.Object) error { return nil }, nil)
+		require.ErrorIs(t, err, uplink.ErrBucketNotFound)
+	})
+}
@@ -4,8 +4,12 @@
 package object_test
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
 	"io/ioutil"
 	"os"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -14,6 +18,7 @@ import (
 	"storj.io/common/testcontext"
 	"storj.io/common/testrand"
 	"storj.io/storj/private/testplanet"
+	"storj.io/uplink"
 	"storj.io/uplink/private/object"
 	"storj.io/uplink/private/testuplink"
 )
@@ -66,4 +71,169 @@ func TestDownloadObjectAt(t *testing.T) {
 	})
 }
 
+func TestDownloadObjectAtRange(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project, err := planet.Uplinks[0].OpenProject(ctx, planet.Satellites[0])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		newCtx := testuplink.WithMaxSegmentSize(ctx, 100*memory.KiB)
+		expected := testrand.Bytes(350 * memory.KiB)
+		err = planet.Uplinks[0].Upload(newCtx, planet.Satellites[0], "foo", "object", expected)
+		require.NoError(t, err)
+
+		testCases := []struct {
+			Name           string
+			Offset, Length int64
+		}{
+			{"within segment", 10 * memory.KiB.Int64(), memory.KiB.Int64()},
+			{"across segments", 90 * memory.KiB.Int64(), 120 * memory.KiB.Int64()},
+			{"until end", 290 * memory.KiB.Int64(), 0},
+			{"beyond end", 340 * memory.KiB.Int64(), 100 * memory.KiB.Int64()},
+		}
+
+		for _, tc := range testCases {
+			tc := tc
+			t.Run(tc.Name, func(t *testing.T) {
+				file, err := os.Create(ctx.File(tc.Name))
+				require.NoError(t, err)
+				defer ctx.Check(file.Close)
+
+				err = object.DownloadObjectAt(ctx, project, "foo", "object", file, &object.DownloadObjectAtOptions{
+					Concurrency: 2,
+					Offset:      tc.Offset,
+					Length:      tc.Length,
+				})
+				require.NoError(t, err)
+
+				data, err := ioutil.ReadAll(file)
+				require.NoError(t, err)
+				require.Equal(t, len(expected), len(data))
+
+				limit := int64(len(expected))
+				if tc.Length > 0 && tc.Offset+tc.Length < limit {
+					limit = tc.Offset + tc.Length
+				}
+				require.Equal(t, expected[tc.Offset:limit], data[tc.Offset:limit])
+
+				// the rest of the file is untouched
+				require.Equal(t, make([]byte, tc.Offset), data[:tc.Offset])
+				require.Equal(t, make([]byte, int64(len(expected))-limit), data[limit:])
+			})
+		}
+
+		file, err := os.Create(ctx.File("invalid"))
+		require.NoError(t, err)
+		defer ctx.Check(file.Close)
+
+		err = object.DownloadObjectAt(ctx, project, "foo", "object", file, &object.DownloadObjectAtOptions{
+			Offset: int64(len(expected)),
+		})
+		require.Error(t, err)
+	})
+}
+
+func TestDownloadObjectAtMigrated(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount: 1, StorageNodeCount: 4, UplinkCount: 1,
+		Reconfigure: testplanet.Reconfigure{
+			Satellite: testplanet.MaxSegmentSize(20 * memory.KiB),
+		},
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project, err := planet.Uplinks[0].OpenProject(ctx, planet.Satellites[0])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		// objects uploaded without plain sizes look like objects of old clients
+		expected := testrand.Bytes(110 * memory.KiB)
+		err = planet.Uplinks[0].Upload(testuplink.WithoutPlainSize(ctx), planet.Satellites[0], "foo", "object", expected)
+		require.NoError(t, err)
+
+		for _, offset := range []int64{0, 30 * memory.KiB.Int64()} {
+			file, err := os.Create(ctx.File(strconv.FormatInt(offset, 10)))
+			require.NoError(t, err)
+			defer ctx.Check(file.Close)
+
+			err = object.DownloadObjectAt(ctx, project, "foo", "object", file, &object.DownloadObjectAtOptions{
+				Concurrency: 2,
+				Offset:      offset,
+			})
+			require.NoError(t, err)
+
+			data, err := ioutil.ReadAll(file)
+			require.NoError(t, err)
+			require.Equal(t, expected[offset:], data[offset:])
+		}
+	})
+}
+
+func TestUploadObjectFrom(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project, err := planet.Uplinks[0].OpenProject(ctx, planet.Satellites[0])
+		require.NoError(t, err)
+		defer ctx.Check(project.Close)
+
+		_, err = project.CreateBucket(ctx, "foo")
+		require.NoError(t, err)
+
+		testCases := []struct {
+			Name       string
+			ObjectSize memory.Size
+		}{
+			{"empty", 0},
+			{"inline", memory.KiB},
+			{"remote ", 90 * memory.KiB},
+			{"remote + empty inline", 100 * memory.KiB},
+			{"remote + inline", 101 * memory.KiB},
+			{"multiple remotes", 1090 * memory.KiB},
+		}
+
+		for _, tc := range testCases {
+			tc := tc
+			t.Run(tc.Name, func(t *testing.T) {
+				newCtx := testuplink.WithMaxSegmentSize(ctx, 100*memory.KiB)
+				expected := testrand.Bytes(tc.ObjectSize)
+
+				info, err := object.UploadObjectFrom(newCtx, project, "foo", tc.Name, bytes.NewReader(expected), int64(len(expected)), &object.UploadObjectFromOptions{
+					Concurrency:    4,
+					CustomMetadata: uplink.CustomMetadata{"key": "value"},
+				})
+				require.NoError(t, err)
+				require.EqualValues(t, len(expected), info.System.ContentLength)
+
+				data, err := planet.Uplinks[0].Download(ctx, planet.Satellites[0], "foo", tc.Name)
+				require.NoError(t, err)
+				require.Equal(t, expected, data)
+
+				stat, err := project.StatObject(ctx, "foo", tc.Name)
+				require.NoError(t, err)
+				require.Equal(t, uplink.CustomMetadata{"key": "value"}, stat.Custom)
+
+				sha256Sum := sha256.Sum256(expected)
+				require.Equal(t, sha256Sum[:], info.System.SHA256)
+				require.Equal(t, info.System.SHA256, stat.System.SHA256)
+				require.Equal(t, info.System.CRC32C, stat.System.CRC32C)
+			})
+		}
+
+		newCtx := testuplink.WithMaxSegmentSize(ctx, 100*memory.KiB)
+
+		// a reader shorter than the size fails the upload
+		short := testrand.Bytes(250 * memory.KiB)
+		_, err = object.UploadObjectFrom(newCtx, project, "foo", "short", bytes.NewReader(short), 300*memory.KiB.Int64(), nil)
+		require.Error(t, err)
+
+		_, err = project.StatObject(ctx, "foo", "short")
+		require.True(t, errors.Is(err, uplink.ErrObjectNotFound))
+	})
+}
+
 // TODO add test for object with more segments than satellite page size
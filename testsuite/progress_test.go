@@ -0,0 +1,164 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package testsuite_test
+
+import (
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/memory"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/storj/private/testplanet"
+	"storj.io/uplink"
+	"storj.io/uplink/private/testuplink"
+)
+
+// progressRecorder records the reported progress.
+type progressRecorder struct {
+	mu      sync.Mutex
+	reports []uplink.Progress
+}
+
+func (recorder *progressRecorder) Report(progress uplink.Progress) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.reports = append(recorder.reports, progress)
+}
+
+func (recorder *progressRecorder) Last(t *testing.T) uplink.Progress {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	require.NotEmpty(t, recorder.reports)
+	return recorder.reports[len(recorder.reports)-1]
+}
+
+func nodeBytes(progress uplink.Progress) (total int64) {
+	for _, n := range progress.NodeBytes {
+		total += n
+	}
+	return total
+}
+
+func TestProgress(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		newCtx := testuplink.WithMaxSegmentSize(ctx, 10*memory.KiB)
+		expectedData := testrand.Bytes(25 * memory.KiB)
+
+		var uploadProgress progressRecorder
+		upload, err := project.UploadObject(newCtx, "testbucket", "object", &uplink.UploadOptions{
+			Progress:         uploadProgress.Report,
+			ProgressInterval: time.Millisecond,
+		})
+		require.NoError(t, err)
+		_, err = upload.Write(expectedData)
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+
+		last := uploadProgress.Last(t)
+		require.EqualValues(t, len(expectedData), last.PlainBytes)
+		require.EqualValues(t, 3, last.SegmentsCommitted)
+		require.NotEmpty(t, last.NodeBytes)
+		require.True(t, nodeBytes(last) > 0)
+
+		var downloadProgress progressRecorder
+		download, err := project.DownloadObject(ctx, "testbucket", "object", &uplink.DownloadOptions{
+			Length:           -1,
+			Progress:         downloadProgress.Report,
+			ProgressInterval: time.Millisecond,
+		})
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(download)
+		require.NoError(t, err)
+		require.NoError(t, download.Close())
+		require.Equal(t, expectedData, data)
+
+		last = downloadProgress.Last(t)
+		require.EqualValues(t, len(expectedData), last.PlainBytes)
+		require.Zero(t, last.SegmentsCommitted)
+		require.True(t, nodeBytes(last) > 0)
+
+		info, err := project.BeginUpload(ctx, "testbucket", "multipart", nil)
+		require.NoError(t, err)
+
+		var partProgress progressRecorder
+		part, err := project.UploadPart(newCtx, "testbucket", "multipart", info.UploadID, 1)
+		require.NoError(t, err)
+		require.NoError(t, part.SetProgress(partProgress.Report, time.Millisecond))
+		_, err = part.Write(expectedData)
+		require.NoError(t, err)
+		require.NoError(t, part.Commit())
+
+		last = partProgress.Last(t)
+		require.EqualValues(t, len(expectedData), last.PlainBytes)
+		require.EqualValues(t, 3, last.SegmentsCommitted)
+		require.True(t, nodeBytes(last) > 0)
+
+		require.Error(t, part.SetProgress(partProgress.Report, time.Millisecond))
+	})
+}
+
+func TestUploadLongTail(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		newCtx := testuplink.WithMaxSegmentSize(ctx, 10*memory.KiB)
+		expectedData := testrand.Bytes(25 * memory.KiB)
+
+		for _, longTail := range []uplink.UploadLongTail{
+			{WaitForAll: true},
+			{Timeout: time.Millisecond},
+			{Timeout: time.Millisecond, WaitForAll: true},
+		} {
+			longTail := longTail
+
+			var uploadProgress progressRecorder
+			upload, err := project.UploadObject(newCtx, "testbucket", "object", &uplink.UploadOptions{
+				LongTail: &longTail,
+				Progress: uploadProgress.Report,
+			})
+			require.NoError(t, err)
+			_, err = upload.Write(expectedData)
+			require.NoError(t, err)
+			require.NoError(t, upload.Commit())
+
+			last := uploadProgress.Last(t)
+			require.EqualValues(t, 3, last.SegmentsCommitted)
+			require.Zero(t, last.PiecesFailed)
+			if longTail.Timeout == 0 {
+				// all pieces of the remote segments are uploaded
+				require.Positive(t, last.PiecesUploaded)
+				require.Zero(t, last.PiecesUploaded%int64(len(planet.StorageNodes)))
+				require.Zero(t, last.PiecesCanceled)
+			}
+
+			download, err := project.DownloadObject(ctx, "testbucket", "object", nil)
+			require.NoError(t, err)
+			data, err := ioutil.ReadAll(download)
+			require.NoError(t, err)
+			require.NoError(t, download.Close())
+			require.Equal(t, expectedData, data)
+		}
+	})
+}
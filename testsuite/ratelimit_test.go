@@ -0,0 +1,72 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package testsuite_test
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"storj.io/common/memory"
+	"storj.io/common/testcontext"
+	"storj.io/common/testrand"
+	"storj.io/storj/private/testplanet"
+	"storj.io/uplink"
+)
+
+func TestRateLimit(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		// the pieces stored on the nodes are at least as large as the data,
+		// so with the burst of 16KiB the transfers take at least 0.75s.
+		expectedData := testrand.Bytes(64 * memory.KiB)
+		limit := &uplink.RateLimit{
+			BytesPerSecond: 64 * memory.KiB.Int64(),
+			Burst:          16 * memory.KiB.Int64(),
+		}
+
+		start := time.Now()
+		upload, err := project.UploadObject(ctx, "testbucket", "object", &uplink.UploadOptions{
+			RateLimit: limit,
+		})
+		require.NoError(t, err)
+		_, err = upload.Write(expectedData)
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+		require.True(t, time.Since(start) >= 500*time.Millisecond, "upload wasn't limited")
+
+		start = time.Now()
+		download, err := project.DownloadObject(ctx, "testbucket", "object", &uplink.DownloadOptions{
+			Length:    -1,
+			RateLimit: limit,
+		})
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(download)
+		require.NoError(t, err)
+		require.NoError(t, download.Close())
+		require.True(t, time.Since(start) >= 500*time.Millisecond, "download wasn't limited")
+		require.Equal(t, expectedData, data)
+
+		// a zero rate disables the limit of the project.
+		download, err = project.DownloadObject(ctx, "testbucket", "object", &uplink.DownloadOptions{
+			Length:    -1,
+			RateLimit: &uplink.RateLimit{},
+		})
+		require.NoError(t, err)
+		data, err = ioutil.ReadAll(download)
+		require.NoError(t, err)
+		require.NoError(t, download.Close())
+		require.Equal(t, expectedData, data)
+	})
+}
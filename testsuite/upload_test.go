@@ -19,6 +19,7 @@ import (
 	"storj.io/common/testrand"
 	"storj.io/storj/private/testplanet"
 	"storj.io/uplink"
+	"storj.io/uplink/private/testuplink"
 )
 
 func TestSetMetadata(t *testing.T) {
@@ -96,6 +97,8 @@ func TestSetMetadata(t *testing.T) {
 			require.WithinDuration(t, time.Now(), listObject.System.Created, 1*time.Minute)
 			require.Equal(t, memory.KiB.Int64(), listObject.System.ContentLength)
 			require.Equal(t, expectedCustomMetadata, listObject.Custom)
+			require.Equal(t, upload.Info().System.SHA256, listObject.System.SHA256)
+			require.Equal(t, upload.Info().System.CRC32C, listObject.System.CRC32C)
 		}
 		{ // test metadata from ListObjects and disabled standard and custom metadata
 			objects := project.ListObjects(ctx, bucket.Name, &uplink.ListObjectsOptions{
@@ -413,3 +416,91 @@ func TestConcurrentUploadToSamePath(t *testing.T) {
 		require.Equal(t, expectedData, downloaded)
 	})
 }
+
+func TestUploadObject_Concurrency(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		newCtx := testuplink.WithMaxSegmentSize(ctx, 10*memory.KiB)
+
+		for _, size := range []memory.Size{0, memory.KiB, 10 * memory.KiB, 11 * memory.KiB, 95 * memory.KiB} {
+			size := size
+			t.Run(size.String(), func(t *testing.T) {
+				expectedData := testrand.Bytes(size)
+
+				upload, err := project.UploadObject(newCtx, "testbucket", size.String(), &uplink.UploadOptions{
+					Concurrency: 4,
+				})
+				require.NoError(t, err)
+
+				_, err = upload.Write(expectedData)
+				require.NoError(t, err)
+				require.NoError(t, upload.Commit())
+				require.Equal(t, size.Int64(), upload.Info().System.ContentLength)
+
+				downloaded, err := planet.Uplinks[0].Download(ctx, planet.Satellites[0], "testbucket", size.String())
+				require.NoError(t, err)
+				require.Equal(t, expectedData, downloaded)
+			})
+		}
+	})
+}
+
+func TestResumeUpload(t *testing.T) {
+	testplanet.Run(t, testplanet.Config{
+		SatelliteCount:   1,
+		StorageNodeCount: 4,
+		UplinkCount:      1,
+	}, func(t *testing.T, ctx *testcontext.Context, planet *testplanet.Planet) {
+		project := openProject(t, ctx, planet)
+		defer ctx.Check(project.Close)
+
+		createBucket(t, ctx, project, "testbucket")
+
+		newCtx := testuplink.WithMaxSegmentSize(ctx, 10*memory.KiB)
+
+		expectedData := testrand.Bytes(45 * memory.KiB)
+		journal := uplink.NewFileUploadJournal(ctx.File("upload.journal"))
+
+		upload, err := project.UploadObject(newCtx, "testbucket", "resumed", &uplink.UploadOptions{
+			Journal: journal,
+		})
+		require.NoError(t, err)
+
+		_, err = upload.Write(expectedData[:35*memory.KiB])
+		require.NoError(t, err)
+		require.NoError(t, upload.Abort())
+
+		state, err := journal.Load()
+		require.NoError(t, err)
+		require.NotEmpty(t, state.UploadID)
+		require.False(t, state.Committed)
+		require.True(t, state.Offset() <= 35*memory.KiB.Int64())
+
+		upload, err = project.ResumeUpload(newCtx, "testbucket", "resumed", journal, nil)
+		require.NoError(t, err)
+
+		_, err = upload.Write(expectedData[state.Offset():])
+		require.NoError(t, err)
+		require.NoError(t, upload.Commit())
+		require.Equal(t, int64(len(expectedData)), upload.Info().System.ContentLength)
+
+		state, err = journal.Load()
+		require.NoError(t, err)
+		require.True(t, state.Committed)
+
+		_, err = project.ResumeUpload(newCtx, "testbucket", "resumed", journal, nil)
+		require.True(t, errors.Is(err, uplink.ErrUploadDone))
+
+		downloaded, err := planet.Uplinks[0].Download(ctx, planet.Satellites[0], "testbucket", "resumed")
+		require.NoError(t, err)
+		require.Equal(t, expectedData, downloaded)
+	})
+}
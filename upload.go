@@ -6,12 +6,17 @@ package uplink
 import (
 	"context"
 	"errors"
+	"io"
 	"sync"
 	"time"
 
 	"github.com/zeebo/errs"
 
 	"storj.io/common/pb"
+	"storj.io/uplink/private/compression"
+	"storj.io/uplink/private/eestream"
+	"storj.io/uplink/private/progress"
+	"storj.io/uplink/private/ratelimit"
 	"storj.io/uplink/private/storage/streams"
 	"storj.io/uplink/private/stream"
 )
@@ -23,6 +28,44 @@ var ErrUploadDone = errors.New("upload done")
 type UploadOptions struct {
 	// When Expires is zero, there is no expiration.
 	Expires time.Time
+
+	// Concurrency is the number of segments which are uploaded in parallel by
+	// UploadObject. Each segment that is being uploaded is buffered in memory,
+	// so the memory used by the upload is up to Concurrency times the segment
+	// size. When Concurrency is less than 2 the segments are uploaded one by one.
+	Concurrency int
+
+	// Journal, when set, records the progress of the upload, so that it can be
+	// continued with Project.ResumeUpload after an interruption. The pending
+	// object is kept when the upload fails or is aborted; use AbortUpload with
+	// the UploadID from the journal to discard it.
+	Journal UploadJournal
+
+	// Compression compresses the data before it's encrypted and uploaded. The
+	// codec is recorded in the encrypted metadata and DownloadObject
+	// decompresses the data transparently. Compression can't be combined with
+	// Journal.
+	Compression Compression
+
+	// DisableChecksums disables calculating the SHA-256 and CRC32C checksums
+	// of the data. By default they are stored in the encrypted metadata and
+	// verified by DownloadObject. Checksums are never calculated for uploads
+	// with Journal, because a resumed upload doesn't see the whole data.
+	DisableChecksums bool
+
+	// Progress, when not nil, is called with the progress of the upload every
+	// ProgressInterval, which defaults to one second, and once more when the
+	// upload is committed or aborted. It's called from a separate goroutine.
+	Progress         func(Progress)
+	ProgressInterval time.Duration
+
+	// RateLimit, when not nil, overrides Config.UploadRateLimit for this
+	// upload. The upload isn't counted towards the limit of the project.
+	RateLimit *RateLimit
+
+	// LongTail, when not nil, overrides Config.UploadLongTail for this upload.
+	// The outcome of the piece uploads is reported by Progress.
+	LongTail *UploadLongTail
 }
 
 // UploadObject starts an upload to the specific key.
@@ -40,6 +83,100 @@ func (project *Project) UploadObject(ctx context.Context, bucket, key string, op
 		options = &UploadOptions{}
 	}
 
+	if !options.Compression.valid() {
+		return nil, packageError.New("unsupported compression %q", options.Compression)
+	}
+	if options.Compression != CompressionNone && options.Journal != nil {
+		return nil, packageError.New("compression is not supported for resumable uploads")
+	}
+
+	var journal *streamsJournal
+	if options.Journal != nil {
+		journal = &streamsJournal{
+			journal: options.Journal,
+			state:   UploadJournalState{Bucket: bucket, Key: key},
+		}
+	}
+
+	return project.startUpload(ctx, bucket, key, options, uploadStart{journal: journal})
+}
+
+// ResumeUpload continues an upload which has been recorded in the journal.
+//
+// The data written to the returned upload must start at the offset returned by
+// UploadJournalState.Offset of the journal state. When the journal doesn't
+// contain any started upload, a new upload is started.
+//
+// Returns ErrUploadDone when the upload in the journal has been already committed.
+func (project *Project) ResumeUpload(ctx context.Context, bucket, key string, journal UploadJournal, options *UploadOptions) (upload *Upload, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if bucket == "" {
+		return nil, errwrapf("%w (%q)", ErrBucketNameInvalid, bucket)
+	}
+	if key == "" {
+		return nil, errwrapf("%w (%q)", ErrObjectKeyInvalid, key)
+	}
+	if journal == nil {
+		return nil, packageError.New("journal is nil")
+	}
+
+	if options == nil {
+		options = &UploadOptions{}
+	}
+	if options.Compression != CompressionNone {
+		return nil, packageError.New("compression is not supported for resumable uploads")
+	}
+
+	state, err := journal.Load()
+	if err != nil {
+		return nil, packageError.Wrap(err)
+	}
+
+	if state.UploadID == "" {
+		return project.startUpload(ctx, bucket, key, options, uploadStart{
+			journal: &streamsJournal{
+				journal: journal,
+				state:   UploadJournalState{Bucket: bucket, Key: key},
+			},
+		})
+	}
+
+	if state.Bucket != bucket || state.Key != key {
+		return nil, packageError.New("journal is for a different object (%q/%q)", state.Bucket, state.Key)
+	}
+	if state.Committed {
+		return nil, errwrapf("%w: already committed", ErrUploadDone)
+	}
+
+	resume, err := state.resumeState()
+	if err != nil {
+		return nil, err
+	}
+
+	return project.startUpload(ctx, bucket, key, options, uploadStart{
+		journal: &streamsJournal{
+			journal: journal,
+			state:   state,
+		},
+		resume: resume,
+	})
+}
+
+// uploadStart contains how an upload is started, besides its options.
+type uploadStart struct {
+	journal *streamsJournal
+	resume  *streams.ResumeState
+
+	// source, when not nil, is uploaded instead of the written data. The
+	// custom metadata is set before the upload starts, because the data may
+	// be uploaded before it could be set with SetCustomMetadata.
+	source *io.SectionReader
+	custom CustomMetadata
+}
+
+// startUpload starts or resumes an upload to the specific key.
+func (project *Project) startUpload(ctx context.Context, bucket, key string, options *UploadOptions, start uploadStart) (upload *Upload, err error) {
 	// N.B. we always call dbCleanup which closes the db because
 	// closing it earlier has the benefit of returning a connection to
 	// the pool, so we try to do that as early as possible.
@@ -64,8 +201,26 @@ func (project *Project) UploadObject(ctx context.Context, bucket, key string, op
 		bucket: bucket,
 		object: convertObject(&info),
 	}
+	if start.custom != nil {
+		upload.object.Custom = start.custom.Clone()
+	}
+
+	if options.RateLimit != nil {
+		ctx = ratelimit.WithLimiter(ctx, options.RateLimit.limiter())
+	}
+	if options.LongTail != nil {
+		ctx = eestream.WithUploadLongTail(ctx, options.LongTail.config())
+	}
+
+	ctx, upload.tracker, upload.reporter = startProgress(ctx, options.Progress, options.ProgressInterval)
+	reporter := upload.reporter
+	defer func() {
+		if err != nil {
+			reporter.Stop()
+		}
+	}()
 
-	meta := dynamicMetadata{upload.object}
+	meta := dynamicMetadata{upload}
 	mutableStream, err := obj.CreateDynamicStream(ctx, meta, options.Expires)
 	if err != nil {
 		return nil, convertKnownErrors(err, bucket, key)
@@ -82,16 +237,89 @@ func (project *Project) UploadObject(ctx context.Context, bucket, key string, op
 	}
 
 	upload.streams = streams
-	upload.upload = stream.NewUpload(ctx, mutableStream, streams)
+	putOptions := streamsPutOptions(options)
+	if start.journal != nil {
+		putOptions.Journal = start.journal
+	}
+	putOptions.Resume = start.resume
+	putOptions.Source = start.source
+
+	// the checksums are calculated from the written data or from the source,
+	// while its segments are uploaded.
+	if !options.DisableChecksums && start.journal == nil {
+		upload.checksums = newChecksums()
+		if start.source != nil {
+			upload.checksums.sumSource(ctx, start.source)
+		}
+	}
+
+	upload.upload = stream.NewUploadWithOptions(ctx, mutableStream, streams, putOptions)
+
+	if options.Compression != CompressionNone {
+		upload.compressor, err = compression.NewWriter(upload.upload, compression.Codec(options.Compression), 0)
+		if err != nil {
+			return nil, packageError.Wrap(errs.Combine(err, upload.upload.Abort(), streams.Close()))
+		}
+	}
 
 	return upload, nil
 }
 
-type dynamicMetadata struct{ *Object }
+//nolint:deadcode
+//lint:ignore U1000 its used in private/object package
+func uploadObjectFromWithProject(ctx context.Context, project *Project, bucket, key string, source *io.SectionReader, custom CustomMetadata, options *UploadOptions) (_ *Object, err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if bucket == "" {
+		return nil, errwrapf("%w (%q)", ErrBucketNameInvalid, bucket)
+	}
+	if key == "" {
+		return nil, errwrapf("%w (%q)", ErrObjectKeyInvalid, key)
+	}
+	if custom != nil {
+		if err := custom.Verify(); err != nil {
+			return nil, packageError.Wrap(err)
+		}
+	}
+
+	upload, err := project.startUpload(ctx, bucket, key, options, uploadStart{
+		source: source,
+		custom: custom,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := upload.Commit(); err != nil {
+		return nil, err
+	}
+	return upload.Info(), nil
+}
+
+// streamsPutOptions converts upload options to options of the streams store.
+func streamsPutOptions(options *UploadOptions) streams.PutOptions {
+	return streams.PutOptions{
+		Concurrency: options.Concurrency,
+	}
+}
+
+type dynamicMetadata struct{ upload *Upload }
 
 func (dyn dynamicMetadata) Metadata() ([]byte, error) {
+	userDefined := dyn.upload.object.Custom.Clone()
+	if dyn.upload.checksums != nil {
+		// the data stream has ended, so the checksums are complete, once
+		// the source has been read.
+		if err := dyn.upload.checksums.wait(); err != nil {
+			return nil, err
+		}
+		dyn.upload.checksums.addToMetadata(userDefined)
+	}
+	if dyn.upload.compressor != nil {
+		// the compressor has been closed before the end of the data stream
+		dyn.upload.compressor.Info().AddToMetadata(userDefined)
+	}
 	return pb.Marshal(&pb.SerializableMeta{
-		UserDefined: dyn.Object.Custom.Clone(),
+		UserDefined: userDefined,
 	})
 }
 
@@ -105,6 +333,12 @@ type Upload struct {
 	bucket  string
 	object  *Object
 	streams *streams.Store
+
+	compressor *compression.Writer
+	checksums  *checksums
+
+	tracker  *progress.Tracker
+	reporter *progress.Reporter
 }
 
 // Info returns the last information about the uploaded object.
@@ -113,6 +347,15 @@ func (upload *Upload) Info() *Object {
 	if meta != nil {
 		upload.object.System.ContentLength = meta.Size
 		upload.object.System.Created = meta.Modified
+		if upload.compressor != nil {
+			info := upload.compressor.Info()
+			upload.object.System.Compression = Compression(info.Codec)
+			upload.object.System.UncompressedLength = info.Size
+		}
+		if upload.checksums != nil {
+			upload.object.System.SHA256 = upload.checksums.sha256.Sum(nil)
+			upload.object.System.CRC32C = upload.checksums.crc32c.Sum(nil)
+		}
 	}
 	return upload.object
 }
@@ -121,7 +364,15 @@ func (upload *Upload) Info() *Object {
 // It returns the number of bytes written from p (0 <= n <= len(p))
 // and any error encountered that caused the write to stop early.
 func (upload *Upload) Write(p []byte) (n int, err error) {
-	n, err = upload.upload.Write(p)
+	if upload.compressor != nil {
+		n, err = upload.compressor.Write(p)
+	} else {
+		n, err = upload.upload.Write(p)
+	}
+	if upload.checksums != nil {
+		_, _ = upload.checksums.Write(p[:n])
+	}
+	upload.tracker.AddPlainBytes(int64(n))
 	return n, convertKnownErrors(err, upload.bucket, upload.object.Key)
 }
 
@@ -141,6 +392,15 @@ func (upload *Upload) Commit() error {
 	}
 
 	upload.closed = true
+	defer upload.reporter.Stop()
+
+	if upload.compressor != nil {
+		if err := upload.compressor.Close(); err != nil {
+			upload.cancel()
+			err = errs.Combine(err, upload.upload.Abort(), upload.streams.Close())
+			return convertKnownErrors(err, upload.bucket, upload.object.Key)
+		}
+	}
 
 	err := errs.Combine(
 		upload.upload.Close(),
@@ -167,6 +427,7 @@ func (upload *Upload) Abort() error {
 
 	upload.aborted = true
 	upload.cancel()
+	defer upload.reporter.Stop()
 
 	err := errs.Combine(
 		upload.upload.Abort(),
@@ -0,0 +1,178 @@
+// Copyright (C) 2021 Storj Labs, Inc.
+// See LICENSE for copying information.
+
+package uplink
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"storj.io/common/sync2"
+)
+
+// defaultWalkConcurrency is the number of prefixes listed in parallel by
+// Walk, when it isn't set.
+const defaultWalkConcurrency = 8
+
+// WalkOptions defines options for walking the objects.
+type WalkOptions struct {
+	// Concurrency is the number of prefixes listed in parallel. It defaults
+	// to 8.
+	Concurrency int
+
+	// System includes SystemMetadata in the results.
+	System bool
+	// Custom includes CustomMetadata in the results.
+	Custom bool
+}
+
+// Walk calls fn for each object under the prefix, like listing them with
+// Recursive. The keyspace is split by the collapsed prefixes and the
+// sub-prefixes are listed concurrently, so buckets whose keys have many
+// prefixes are walked much faster. Each object is passed to fn exactly once,
+// but in no particular order.
+//
+// fn is called from one goroutine at a time. When it returns an error, the
+// walk is stopped and Walk returns the error. If not empty, prefix must end
+// with slash.
+func (project *Project) Walk(ctx context.Context, bucket, prefix string, fn func(*Object) error, options *WalkOptions) (err error) {
+	defer mon.Task()(&ctx)(&err)
+
+	if bucket == "" {
+		return errwrapf("%w (%q)", ErrBucketNameInvalid, bucket)
+	}
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		return packageError.New("prefix must end with slash, got %q", prefix)
+	}
+
+	concurrency := defaultWalkConcurrency
+	if options != nil && options.Concurrency > 0 {
+		concurrency = options.Concurrency
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := &walker{
+		ctx:     ctx,
+		cancel:  cancel,
+		project: project,
+		bucket:  bucket,
+		fn:      fn,
+		queue:   []string{prefix},
+	}
+	if options != nil {
+		w.system, w.custom = options.System, options.Custom
+	}
+	w.cond.L = &w.mu
+
+	limiter := sync2.NewLimiter(concurrency)
+	for {
+		prefix, ok := w.next()
+		if !ok {
+			break
+		}
+		if !limiter.Go(ctx, func() { w.list(prefix) }) {
+			w.done(ctx.Err())
+			break
+		}
+	}
+	limiter.Wait()
+
+	return w.err
+}
+
+// walker keeps the state of a walk.
+type walker struct {
+	ctx     context.Context
+	cancel  func()
+	project *Project
+	bucket  string
+	system  bool
+	custom  bool
+
+	fnMu sync.Mutex
+	fn   func(*Object) error
+
+	mu     sync.Mutex
+	cond   sync.Cond
+	queue  []string // prefixes, which haven't been listed yet
+	active int      // number of prefixes being listed
+	err    error
+}
+
+// next returns the next prefix to list. It waits for the active listings,
+// when there's none queued, and returns false, when the walk is finished or
+// has failed.
+func (w *walker) next() (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for len(w.queue) == 0 && w.active > 0 && w.err == nil {
+		w.cond.Wait()
+	}
+	if w.err != nil || len(w.queue) == 0 {
+		return "", false
+	}
+
+	prefix := w.queue[len(w.queue)-1]
+	w.queue = w.queue[:len(w.queue)-1]
+	w.active++
+	return prefix, true
+}
+
+// list lists the objects directly under the prefix and queues its
+// sub-prefixes.
+func (w *walker) list(prefix string) {
+	objects := w.project.ListObjects(w.ctx, w.bucket, &ListObjectsOptions{
+		Prefix: prefix,
+		System: w.system,
+		Custom: w.custom,
+	})
+	for objects.Next() {
+		object := objects.Item()
+		if object.IsPrefix {
+			w.push(object.Key)
+			continue
+		}
+		if err := w.call(object); err != nil {
+			w.done(err)
+			return
+		}
+	}
+	w.done(objects.Err())
+}
+
+// push queues the prefix for listing.
+func (w *walker) push(prefix string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.queue = append(w.queue, prefix)
+	w.cond.Broadcast()
+}
+
+// call calls fn with the object, unless the walk has failed.
+func (w *walker) call(object *Object) error {
+	w.fnMu.Lock()
+	defer w.fnMu.Unlock()
+
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	return w.fn(object)
+}
+
+// done finishes the listing of a prefix. The first error stops the walk.
+func (w *walker) done(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.active--
+	if err != nil && w.err == nil {
+		w.err = err
+		w.cancel()
+	}
+	w.cond.Broadcast()
+}